@@ -0,0 +1,78 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnalysesFollowEntries(t *testing.T) {
+	dir := t.TempDir()
+	d1, d2, d3 := filepath.Join(dir, "d1"), filepath.Join(dir, "d2"), filepath.Join(dir, "d3")
+	for _, d := range []string{d1, d2, d3} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	command := func(d string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(d, "pathed-tool"), nil, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	command(d1)
+	m := newTestModel(t, []string{d1, d2}, nil)
+	matches := func() int { return len(m.analyzeShadows().which("pathed-tool")) }
+
+	first := m.analyzeShadows()
+	if n := matches(); n != 1 {
+		t.Fatalf("matches = %d, want 1", n)
+	}
+	command(d2)
+	if m.analyzeShadows() != first || matches() != 1 {
+		t.Error("analysis recomputed although the entries didn't change")
+	}
+
+	// Editing an entry away and back reads its directory again
+	m.checkpoint()
+	m.paths[1].path = d3
+	if n := matches(); n != 1 {
+		t.Errorf("after the edit: matches = %d, want 1", n)
+	}
+	m.undo()
+	if n := matches(); n != 2 {
+		t.Errorf("after undo: matches = %d, want 2", n)
+	}
+
+	// A rescan picks up changes to unchanged entries
+	command(d3)
+	m.paths[1].path = d3
+	m.analyzeShadows()
+	if err := os.Remove(filepath.Join(d3, "pathed-tool")); err != nil {
+		t.Fatal(err)
+	}
+	if n := matches(); n != 2 {
+		t.Errorf("before the rescan: matches = %d, want 2", n)
+	}
+	m.rescan()
+	if n := matches(); n != 1 {
+		t.Errorf("after the rescan: matches = %d, want 1", n)
+	}
+}
+
+func TestRescanExists(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "later")
+	m := newTestModel(t, []string{dir}, nil)
+	if m.paths[0].exists {
+		t.Fatal("missing directory exists")
+	}
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m.rescan()
+	if !m.paths[0].exists {
+		t.Error("directory created on disk still missing after the rescan")
+	}
+}
@@ -6,10 +6,13 @@ const (
 	ansiBold      = "\x1b[1m"
 	ansiUnderline = "\x1b[4m"
 	ansiNoUnder   = "\x1b[24m"
+	ansiReverse   = "\x1b[7m"
+	ansiNoReverse = "\x1b[27m"
 	ansiRed       = "\x1b[31m"
 	ansiGreen     = "\x1b[32m"
 	ansiYellow    = "\x1b[33m"
 	ansiBlue      = "\x1b[34m"
+	ansiGrey      = "\x1b[90m"
 	ansiBgWhite   = "\x1b[47m"
 	ansiBgGrey    = "\x1b[100m"
 	ansiBgRed     = "\x1b[101m"   // light red background
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// severity ranks audit findings; the zero value means no finding
+type severity int
+
+const (
+	severityNone severity = iota
+	severityLow
+	severityMedium
+	severityHigh
+)
+
+func (s severity) String() string {
+	switch s {
+	case severityLow:
+		return "low"
+	case severityMedium:
+		return "medium"
+	case severityHigh:
+		return "high"
+	}
+	return "none"
+}
+
+// parseSeverity parses a severity name as printed by String
+func parseSeverity(name string) (severity, error) {
+	for s := severityLow; s <= severityHigh; s++ {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return severityNone, fmt.Errorf("invalid severity %q (expected low, medium or high)", name)
+}
+
+// auditFinding is a security problem with one PATH entry
+type auditFinding struct {
+	index    int
+	severity severity
+	message  string
+}
+
+// underDir reports whether path is dir or inside it, comparing normalized paths
+func underDir(path, dir string) bool {
+	path, dir = normalizePath(path), normalizePath(strings.TrimRight(dir, `/\`))
+	return dir != "" && (path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator)))
+}
+
+// auditPaths checks every non-deleted entry of v for entries that let other users or
+// the current directory inject commands or libraries: empty and relative entries,
+// world-writable directories, directories owned by another user, and temporary
+// directories, which are worst when they precede the system directories on PATH.
+// Empty entries are only flagged if they stand for the current directory by accident.
+func auditPaths(paths []pathEntry, infos infoCache, v pathVar) []auditFinding {
+	// The last system directory on PATH; temporary directories before it can hide system commands
+	lastSystem, lastSystemPath := -1, ""
+	if v.isPath() {
+		for i, p := range paths {
+			for _, dir := range systemDirs() {
+				if !p.deleted && normalizePath(expandPath(p.path)) == normalizePath(dir) {
+					lastSystem, lastSystemPath = i, p.path
+				}
+			}
+		}
+	}
+
+	var findings []auditFinding
+	add := func(i int, sev severity, format string, args ...any) {
+		findings = append(findings, auditFinding{index: i, severity: sev, message: fmt.Sprintf(format, args...)})
+	}
+	for i, p := range paths {
+		if p.deleted {
+			continue
+		}
+		if p.path == "" {
+			if !v.keepEmpty && v.empty == emptyCurrentDir {
+				add(i, severityHigh, "empty entry: resolves to the current directory")
+			}
+			continue
+		}
+		info := infos.get(p.path)
+		if !filepath.IsAbs(info.expanded) {
+			add(i, severityHigh, "relative entry: resolves against the current directory")
+			continue
+		}
+		if info.err == nil && info.worldWritable {
+			add(i, severityHigh, "world-writable directory: any user can add commands")
+		}
+		if info.err == nil && info.otherOwner {
+			add(i, severityMedium, "owned by another user (%s)", info.owner)
+		}
+		for _, tmp := range tempDirs() {
+			if !underDir(info.expanded, tmp) {
+				continue
+			}
+			if i < lastSystem {
+				add(i, severityHigh, "temporary directory precedes system directory %s", lastSystemPath)
+			} else {
+				add(i, severityLow, "temporary directory on %s", v.name)
+			}
+			break
+		}
+	}
+	return findings
+}
+
+// entrySeverities returns the highest finding severity for each entry
+func entrySeverities(findings []auditFinding, count int) []severity {
+	severities := make([]severity, count)
+	for _, f := range findings {
+		severities[f.index] = max(severities[f.index], f.severity)
+	}
+	return severities
+}
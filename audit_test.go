@@ -0,0 +1,124 @@
+//go:build !windows
+
+package main
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// auditFixture returns an infoCache describing directories without touching the disk
+func auditFixture(infos map[string]dirInfo) infoCache {
+	cache := infoCache{}
+	for path, info := range infos {
+		info.expanded = path
+		cache[path] = info
+	}
+	return cache
+}
+
+func TestAuditPaths(t *testing.T) {
+	infos := auditFixture(map[string]dirInfo{
+		"/usr/bin":  {},
+		"/home/u":   {},
+		"/shared":   {worldWritable: true},
+		"/opt/bob":  {otherOwner: true, owner: "bob"},
+		"/tmp/a":    {},
+		"/tmp/b":    {},
+		"/tmpfiles": {},
+	})
+	entries := func(paths ...string) []pathEntry {
+		var out []pathEntry
+		for _, p := range paths {
+			out = append(out, pathEntry{path: p})
+		}
+		return out
+	}
+	tests := []struct {
+		name  string
+		paths []pathEntry
+		v     pathVar
+		want  []string // "index severity"
+	}{
+		{
+			name:  "clean",
+			paths: entries("/home/u", "/usr/bin"),
+			v:     pathVariable,
+		},
+		{
+			name:  "empty and relative entries",
+			paths: entries("/usr/bin", "", "bin", "./x"),
+			v:     pathVariable,
+			want:  []string{"1 high", "2 high", "3 high"},
+		},
+		{
+			name:  "world-writable and other owner",
+			paths: entries("/shared", "/opt/bob"),
+			v:     pathVariable,
+			want:  []string{"0 high", "1 medium"},
+		},
+		{
+			name:  "temporary directories before and after the system directories",
+			paths: entries("/tmp/a", "/usr/bin", "/tmp/b", "/tmpfiles"),
+			v:     pathVariable,
+			want:  []string{"0 high", "2 low"},
+		},
+		{
+			name:  "deleted entries are ignored",
+			paths: []pathEntry{{path: "", deleted: true}, {path: "/shared", deleted: true}},
+			v:     pathVariable,
+		},
+		{
+			name:  "meaningful empty segment",
+			paths: entries("/usr/share/man", ""),
+			v:     lookupVar("MANPATH"),
+		},
+		{
+			name:  "temporary directory on another variable",
+			paths: entries("/tmp/a", "/usr/bin"),
+			v:     lookupVar("LD_LIBRARY_PATH"),
+			want:  []string{"0 low"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range auditPaths(tt.paths, infos, tt.v) {
+				got = append(got, strconv.Itoa(f.index)+" "+f.severity.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("findings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntrySeverities(t *testing.T) {
+	findings := []auditFinding{{index: 0, severity: severityLow}, {index: 0, severity: severityHigh}, {index: 2, severity: severityMedium}}
+	if got, want := entrySeverities(findings, 3), []severity{severityHigh, severityNone, severityMedium}; !slices.Equal(got, want) {
+		t.Errorf("entrySeverities() = %v, want %v", got, want)
+	}
+}
+
+func TestFailsAudit(t *testing.T) {
+	findings := []auditFinding{{severity: severityLow}, {severity: severityMedium}}
+	for _, tt := range []struct {
+		failOn string
+		want   bool
+	}{{"low", true}, {"medium", true}, {"high", false}} {
+		failOn, err := parseSeverity(tt.failOn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := failsAudit(findings, failOn); got != tt.want {
+			t.Errorf("failsAudit(--fail-on %s) = %v, want %v", tt.failOn, got, tt.want)
+		}
+	}
+	if failsAudit(nil, severityLow) {
+		t.Error("failsAudit(no findings) = true")
+	}
+	if _, err := parseSeverity("none"); err == nil {
+		t.Error("parseSeverity(none) = nil, want an error")
+	}
+}
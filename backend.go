@@ -0,0 +1,282 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// backend reads and writes the entries of a PATH-like variable for one kind of store
+type backend interface {
+	// Describe returns a short human-readable description of the store
+	Describe() string
+	// Var returns the variable the backend edits
+	Var() pathVar
+	// Sections returns the sections Save persists, in PATH order
+	Sections() []string
+	// Load reads the current entries from the store
+	Load() ([]pathEntry, error)
+	// Save writes entries to the store, skipping deleted ones
+	Save(paths []pathEntry) error
+}
+
+// envBackend reads a variable from the process environment. Instead of persisting,
+// Save prints the value for the calling shell to capture, or a statement setting it
+// in the chosen output format.
+type envBackend struct {
+	v      pathVar
+	format string // one of outputFormats, or "" for the bare value
+}
+
+func (envBackend) Describe() string             { return "environment" }
+func (b envBackend) Var() pathVar               { return b.v }
+func (envBackend) Sections() []string           { return []string{""} }
+func (b envBackend) Load() ([]pathEntry, error) { return loadPathsFromEnv(b.v), nil }
+func (b envBackend) Save(paths []pathEntry) error {
+	return b.print(sectionPaths(paths, ""))
+}
+
+// print writes entries to stdout in the backend's output format. Under the shell
+// integration function (see "pathed init"), the statement goes to its file instead,
+// unless a format was asked for explicitly.
+func (b envBackend) print(entries []string) error {
+	if file := os.Getenv(evalFileEnv); file != "" && b.format == "" {
+		return writeEvalFile(file, entries, b.v, os.Getenv(evalFormatEnv))
+	}
+	value, err := formatValue(entries, b.v, b.format)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(value)
+	return err
+}
+
+// namesVar returns true if the output names the variable it sets, so the editor can
+// switch to another one: every format but the bare value and lines, including the
+// statement written for the shell integration
+func (b envBackend) namesVar() bool {
+	format := b.format
+	if format == "" && os.Getenv(evalFileEnv) != "" {
+		format = os.Getenv(evalFormatEnv)
+	}
+	return format != "" && format != "lines"
+}
+
+// profileBackend stores user entries of a variable in a managed block of the shell profile
+type profileBackend struct {
+	v pathVar
+}
+
+func (profileBackend) Describe() string {
+	file, err := profileFile()
+	if err != nil {
+		return "shell profile"
+	}
+	return "shell profile (" + file + ")"
+}
+func (b profileBackend) Var() pathVar                 { return b.v }
+func (profileBackend) Sections() []string             { return []string{"user"} }
+func (b profileBackend) Load() ([]pathEntry, error)   { return loadPathsFromProfile(b.v) }
+func (b profileBackend) Save(paths []pathEntry) error { return saveProfilePaths(paths, b.v) }
+func (b profileBackend) notice() string {
+	file, _ := profileFile()
+	return fmt.Sprintf("Saved to %s - open a new shell or run: . %s", file, file)
+}
+
+// fishBackend stores user PATH entries in fish's fish_user_paths universal variable
+type fishBackend struct{}
+
+func (fishBackend) Describe() string {
+	file, err := fishVariablesFile()
+	if err != nil {
+		return "fish_user_paths"
+	}
+	return "fish_user_paths (" + file + ")"
+}
+func (fishBackend) Var() pathVar                 { return pathVariable }
+func (fishBackend) Sections() []string           { return []string{"user"} }
+func (fishBackend) Load() ([]pathEntry, error)   { return loadPathsFromFish() }
+func (fishBackend) Save(paths []pathEntry) error { return saveFishPaths(paths) }
+func (b fishBackend) notice() string {
+	file, _ := fishVariablesFile()
+	return fmt.Sprintf("Saved fish_user_paths to %s - start a new fish session to pick up the change", file)
+}
+
+// testRegistry replaces the Windows registry in registryFor when set. Tests set it to
+// an in-memory store, so registry mode can be exercised on any platform.
+var testRegistry registryStore
+
+// registryFor returns the registry backend for v and whether registry mode is
+// available
+func registryFor(v pathVar) (registryBackend, bool) {
+	if testRegistry != nil {
+		return registryBackend{store: testRegistry, v: v}, true
+	}
+	return registryBackend{store: winRegistry{}, v: v}, supportsRegistry
+}
+
+// selectBackend returns the backend for v chosen by the mode flags, defaulting to the
+// environment
+func selectBackend(registry, profile, fish bool, v pathVar) (backend, error) {
+	count := 0
+	for _, set := range []bool{registry, profile, fish} {
+		if set {
+			count++
+		}
+	}
+	switch {
+	case count > 1:
+		return nil, errors.New("--registry, --profile and --fish cannot be combined")
+	case registry:
+		b, ok := registryFor(v)
+		if !ok {
+			return nil, errors.New("--registry flag is only supported on Windows")
+		}
+		return b, nil
+	case profile:
+		if !supportsProfile {
+			return nil, errors.New("--profile flag is only supported on Linux/macOS")
+		}
+		// The managed block exports the variable by name
+		if err := checkVarName(v.name); err != nil {
+			return nil, err
+		}
+		return profileBackend{v: v}, nil
+	case fish:
+		if !v.isPath() {
+			return nil, fmt.Errorf("--fish only edits PATH (fish_user_paths), not %s", v.name)
+		}
+		return fishBackend{}, nil
+	default:
+		return envBackend{v: v}, nil
+	}
+}
+
+// withFormat returns b set to print in the given output format. Only the environment
+// backend prints, so a format is an error for persistent stores. The statements it
+// prints name the variable, so that has to be a valid identifier, even without a
+// format: the shell integration picks one.
+func withFormat(b backend, format string) (backend, error) {
+	env, ok := b.(envBackend)
+	if ok {
+		if err := checkVarName(env.v.name); err != nil {
+			return nil, err
+		}
+	}
+	if format == "" {
+		return b, nil
+	}
+	if err := checkOutputFormat(format); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("--format only applies to default mode; %s saves in place", b.Describe())
+	}
+	env.format = format
+	return env, nil
+}
+
+// withVar returns a backend of the same kind as b that edits v instead
+func withVar(b backend, v pathVar) (backend, error) {
+	switch b := b.(type) {
+	case envBackend:
+		if err := checkVarName(v.name); err != nil {
+			return nil, err
+		}
+		return envBackend{v: v, format: b.format}, nil
+	case registryBackend:
+		return registryBackend{store: b.store, v: v}, nil
+	case profileBackend:
+		if err := checkVarName(v.name); err != nil {
+			return nil, err
+		}
+		return profileBackend{v: v}, nil
+	case fishBackend:
+		if v.isPath() {
+			return b, nil
+		}
+		return nil, fmt.Errorf("fish mode only edits PATH (fish_user_paths), not %s", v.name)
+	}
+	return nil, fmt.Errorf("%s cannot edit %s", b.Describe(), v.name)
+}
+
+// persists returns true if a backend saves changes in place. The env backend
+// only prints PATH for the calling shell to capture.
+func persists(b backend) bool {
+	_, env := b.(envBackend)
+	return !env
+}
+
+// hasSection returns true if a backend persists the given section
+func hasSection(b backend, section string) bool {
+	return slices.Contains(b.Sections(), section)
+}
+
+// inherited returns true if p belongs to a section b doesn't save, like the system
+// entries file-based stores take from the environment. Such entries are read-only.
+func inherited(b backend, p pathEntry) bool {
+	return persists(b) && !hasSection(b, p.source)
+}
+
+// defaultSource returns the source of new entries: "user" for persistent stores,
+// "" for the environment
+func defaultSource(b backend) string {
+	if persists(b) {
+		return "user"
+	}
+	return ""
+}
+
+// persistPaths saves entries through a backend. Persistent stores get a backup of
+// their previous state first, and refuse the save with errStoreChanged if loaded is
+// not "" and no longer the fingerprint of their value.
+func persistPaths(b backend, paths []pathEntry, loaded string) error {
+	if !persists(b) {
+		return b.Save(paths)
+	}
+	return saveWithBackup(b, paths, loaded)
+}
+
+// printPersistNotice tells the user how to pick up a change saved by a backend, if needed
+func printPersistNotice(b backend) {
+	if n, ok := b.(interface{ notice() string }); ok {
+		fmt.Fprintln(os.Stderr, n.notice())
+	}
+}
+
+// availableBackends returns the backends for v usable on this platform
+func availableBackends(v pathVar) []backend {
+	var backends []backend
+	if b, ok := registryFor(v); ok {
+		backends = append(backends, b)
+	}
+	if supportsProfile {
+		backends = append(backends, profileBackend{v: v})
+	}
+	if v.isPath() {
+		backends = append(backends, fishBackend{})
+	}
+	return backends
+}
+
+// findBackend returns the available backend for v with the given description
+func findBackend(desc string, v pathVar) (backend, error) {
+	for _, b := range availableBackends(v) {
+		if b.Describe() == desc {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("store %q is not available for %s", desc, v.name)
+}
+
+// sectionPaths returns the non-deleted paths of one section, in order
+func sectionPaths(paths []pathEntry, section string) []string {
+	var result []string
+	for _, p := range paths {
+		if p.source == section && !p.deleted {
+			result = append(result, p.path)
+		}
+	}
+	return result
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backup is a snapshot of a persisted change, written before the store is modified
+type backup struct {
+	ID       string          `json:"id"`
+	Time     time.Time       `json:"time"`
+	Store    string          `json:"store"`         // backend description, used to find the store on restore
+	Var      string          `json:"var,omitempty"` // variable name; empty for PATH
+	User     string          `json:"user"`
+	Version  string          `json:"version"`
+	Sections []backupSection `json:"sections"`
+}
+
+// backupSection holds the entries of one changed section before and after the change
+type backupSection struct {
+	Name     string   `json:"name"`
+	Previous []string `json:"previous"`
+	New      []string `json:"new"`
+}
+
+// stateDir returns the directory for pathed's persistent state:
+// $PATHED_STATE_DIR, %LOCALAPPDATA%\pathed on Windows, or $XDG_STATE_HOME/pathed
+// (default ~/.local/state/pathed) elsewhere
+func stateDir() (string, error) {
+	if dir := os.Getenv("PATHED_STATE_DIR"); dir != "" {
+		return dir, nil
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return filepath.Join(dir, "pathed"), nil
+		}
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "pathed"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "pathed"), nil
+}
+
+// backupDir returns the directory holding backup snapshots
+func backupDir() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "backups"), nil
+}
+
+// currentUser returns the name of the user running pathed
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// newBackup compares the store's current entries with the entries about to be saved.
+// Returns nil if no persisted section changes.
+func newBackup(b backend, current, paths []pathEntry) *backup {
+	bk := &backup{
+		Time:    time.Now(),
+		Store:   b.Describe(),
+		User:    currentUser(),
+		Version: version,
+	}
+	if v := b.Var(); !v.isPath() {
+		bk.Var = v.name
+	}
+	bk.ID = bk.Time.Format("20060102-150405.000")
+	for _, section := range b.Sections() {
+		previous := sectionPaths(current, section)
+		next := sectionPaths(paths, section)
+		if strings.Join(previous, "\x00") != strings.Join(next, "\x00") {
+			bk.Sections = append(bk.Sections, backupSection{Name: section, Previous: previous, New: next})
+		}
+	}
+	if len(bk.Sections) == 0 {
+		return nil
+	}
+	return bk
+}
+
+// writeBackup stores a snapshot in the backup directory
+func writeBackup(bk *backup) error {
+	dir, err := backupDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	// Claim a unique file name so two saves within the same millisecond can't collide
+	var f *os.File
+	for n := 0; ; n++ {
+		id := bk.ID
+		if n > 0 {
+			id = fmt.Sprintf("%s-%d", bk.ID, n)
+		}
+		f, err = os.OpenFile(filepath.Join(dir, id+".json"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		bk.ID = id
+		break
+	}
+	defer f.Close()
+
+	data, err := json.MarshalIndent(bk, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// saveWithBackup snapshots the store's current entries, then saves paths to it.
+// The save is refused if the snapshot can't be written, or with errStoreChanged if
+// loaded is the fingerprint of the value paths were edited from and the store no
+// longer matches it.
+func saveWithBackup(b backend, paths []pathEntry, loaded string) error {
+	current, err := b.Load()
+	if err != nil {
+		return err
+	}
+	if loaded != "" && fingerprint(b, current) != loaded {
+		return errStoreChanged
+	}
+	if bk := newBackup(b, current, paths); bk != nil {
+		if err := writeBackup(bk); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	return b.Save(paths)
+}
+
+// loadBackups reads all snapshots, oldest first
+func loadBackups() ([]*backup, error) {
+	dir, err := backupDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var backups []*backup
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var bk backup
+		if err := json.Unmarshal(data, &bk); err != nil {
+			return nil, fmt.Errorf("invalid backup %s: %w", f.Name(), err)
+		}
+		backups = append(backups, &bk)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.Before(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// findBackup returns the snapshot with the given ID
+func findBackup(id string) (*backup, error) {
+	backups, err := loadBackups()
+	if err != nil {
+		return nil, err
+	}
+	for _, bk := range backups {
+		if bk.ID == id {
+			return bk, nil
+		}
+	}
+	return nil, fmt.Errorf("no backup with id %q (see 'pathed history')", id)
+}
+
+// variable returns the variable a snapshot was taken of
+func (bk *backup) variable() pathVar {
+	if bk.Var == "" {
+		return pathVariable
+	}
+	return lookupVar(bk.Var)
+}
+
+// restoreEntries returns the store's current entries with the changed sections of a
+// snapshot replaced by their previous values
+func restoreEntries(current []pathEntry, bk *backup) []pathEntry {
+	restored := make(map[string]bool)
+	for _, s := range bk.Sections {
+		restored[s.Name] = true
+	}
+	var paths []pathEntry
+	for _, p := range current {
+		if !restored[p.source] {
+			paths = append(paths, p)
+		}
+	}
+	for _, s := range bk.Sections {
+		for _, p := range s.Previous {
+			paths = append(paths, pathEntry{path: p, source: s.Name, modified: true, exists: bk.variable().exists(p)})
+		}
+	}
+	return paths
+}
+
+// countChanges returns how many entries were added to and removed from a section
+func (s backupSection) countChanges() (added, removed int) {
+	count := make(map[string]int)
+	for _, p := range s.Previous {
+		count[p]++
+	}
+	for _, p := range s.New {
+		count[p]--
+	}
+	for _, n := range count {
+		if n > 0 {
+			removed += n
+		} else {
+			added -= n
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	t.Setenv("PATHED_STATE_DIR", t.TempDir())
+	b, store := newTestRegistry([]string{"/s1"}, []string{"/u1", "/u2"})
+
+	paths, _ := b.Load()
+	paths[1].deleted = true // /u1
+	paths = insertPathEntry(paths, pathEntry{path: "/u3", source: "user", added: true})
+	if err := saveWithBackup(b, paths, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := storedPaths(store, "user"), []string{"/u2", "/u3"}; !slices.Equal(got, want) {
+		t.Fatalf("user after save = %q, want %q", got, want)
+	}
+
+	backups, err := loadBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("got %d backups, want 1", len(backups))
+	}
+	bk := backups[0]
+	if bk.Store != b.Describe() || len(bk.Sections) != 1 || bk.Sections[0].Name != "user" {
+		t.Fatalf("backup = %+v, want one user section of %s", bk, b.Describe())
+	}
+	if got, want := bk.Sections[0].Previous, []string{"/u1", "/u2"}; !slices.Equal(got, want) {
+		t.Errorf("previous = %q, want %q", got, want)
+	}
+	if added, removed := bk.Sections[0].countChanges(); added != 1 || removed != 1 {
+		t.Errorf("countChanges() = +%d -%d, want +1 -1", added, removed)
+	}
+
+	current, _ := b.Load()
+	if err := saveWithBackup(b, restoreEntries(current, bk), ""); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := storedPaths(store, "user"), []string{"/u1", "/u2"}; !slices.Equal(got, want) {
+		t.Errorf("user after restore = %q, want %q", got, want)
+	}
+	if got, want := storedPaths(store, "system"), []string{"/s1"}; !slices.Equal(got, want) {
+		t.Errorf("system after restore = %q, want %q", got, want)
+	}
+
+	// The restore is backed up too, so it can be undone the same way
+	if backups, _ = loadBackups(); len(backups) != 2 {
+		t.Errorf("got %d backups after restore, want 2", len(backups))
+	}
+}
+
+func TestFindBackendTestRegistry(t *testing.T) {
+	reg, store := newTestRegistry(nil, nil)
+	useTestRegistry(t, store)
+	want := reg.Describe()
+	b, err := findBackend(want, pathVariable)
+	if err != nil {
+		t.Fatalf("findBackend(%q) = %v", want, err)
+	}
+	if _, ok := b.(registryBackend); !ok {
+		t.Errorf("findBackend(%q) = %T, want registryBackend", want, b)
+	}
+}
@@ -18,6 +18,7 @@ type browser struct {
 	editingIndex  int    // which path entry we're editing (-1 for add mode)
 	addSource     string // "user" or "system" when adding new entry (empty when editing)
 	showingDrives bool   // true when showing drive selector (Windows only)
+	typePath      bool   // true when closed to continue in the line editor
 }
 
 func newBrowser(startPath string, editingIndex int, height int) *browser {
@@ -253,6 +254,18 @@ func (b *browser) Update(msg tea.KeyMsg) (*browser, tea.Cmd, string) {
 		}
 		return nil, nil, b.currentDir
 
+	case keyTypePath:
+		// Close and continue by typing the path, starting from the current directory
+		b.typePath = true
+		if b.showingDrives && len(b.entries) > 0 {
+			return nil, nil, b.entries[b.list.cursor] + "\\"
+		}
+		dir := b.currentDir
+		if !strings.HasSuffix(dir, string(filepath.Separator)) {
+			dir += string(filepath.Separator)
+		}
+		return nil, nil, dir
+
 	case keyEsc:
 		// Cancel
 		return nil, nil, ""
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// cliFlag describes an option accepted by a subcommand
+type cliFlag struct {
+	name  string // long name without dashes, e.g. "format"
+	short string // optional single-letter alias without dash, e.g. "r"
+	arg   string // value placeholder for usage text; empty for boolean flags
+	help  string
+	// complete returns the candidates for the flag's value, given the flags parsed so
+	// far; if nil, alternatives in arg like "text|json" are offered
+	complete func(a *cliArgs) []string
+}
+
+// cliCommand describes a non-interactive subcommand
+type cliCommand struct {
+	name    string
+	args    string // positional argument synopsis for usage text
+	summary string
+	flags   []cliFlag
+	run     func(a *cliArgs) int // returns the process exit code
+	// complete returns the candidates for positional arguments, given the flags parsed so far
+	complete func(a *cliArgs) []string
+}
+
+// cliArgs holds the parsed options and positional arguments of a subcommand
+type cliArgs struct {
+	values     map[string]string // long flag name -> value ("" for boolean flags)
+	positional []string
+}
+
+// has returns true if the flag was given on the command line
+func (a *cliArgs) has(name string) bool {
+	_, ok := a.values[name]
+	return ok
+}
+
+// get returns the value of a flag, or "" if it was not given
+func (a *cliArgs) get(name string) string {
+	return a.values[name]
+}
+
+// modeFlags select a persistent store instead of the environment
+var modeFlags = []cliFlag{
+	{name: "registry", short: "r", help: "Use the Windows registry (Windows only)"},
+	{name: "profile", short: "p", help: "Use the shell profile managed block (Linux/macOS only)"},
+	{name: "fish", help: "Use fish's fish_user_paths universal variable"},
+}
+
+// formatFlag selects the output format of commands that print the edited value in default mode
+var formatFlag = cliFlag{name: "format", short: "f", arg: "FORMAT", help: "Print a statement for posix, fish, nu, pwsh or cmd, or json or lines (default: the bare value)",
+	complete: func(*cliArgs) []string { return outputFormats }}
+
+// varFlag selects the variable to edit, for commands that apply to any PATH-like variable
+var varFlag = cliFlag{name: "var", arg: "NAME", help: "Edit another PATH-like variable, e.g. MANPATH (default: PATH)",
+	complete: func(*cliArgs) []string { return varNames() }}
+
+// commands lists all subcommands, in the order they appear in usage text
+var commands []*cliCommand
+
+func init() {
+	commands = []*cliCommand{
+		{
+			name:    "list",
+			summary: "Print PATH entries with index, source, exists and duplicate flags",
+			flags: append([]cliFlag{
+				{name: "format", short: "f", arg: "text|json|tsv", help: "Output format (default: text)"},
+			}, append(modeFlags, varFlag)...),
+			run: runList,
+		},
+		{
+			name:    "add",
+			args:    "<path>",
+			summary: "Add a PATH entry and print the new PATH (or save it to a persistent store)",
+			flags: append(append([]cliFlag{
+				sourceFlag,
+				{name: "if-missing", help: "Do nothing if the path is already in the section"},
+			}, placementFlags...), append(modeFlags, varFlag, formatFlag)...),
+			run: runAdd,
+		},
+		{
+			name:    "remove",
+			args:    "<entry>...",
+			summary: "Remove PATH entries by path or index and print the new PATH (or save it to a persistent store)",
+			flags: append([]cliFlag{
+				sourceFlag,
+				{name: "if-exists", help: "Do not fail if an entry is not found"},
+			}, append(modeFlags, varFlag, formatFlag)...),
+			run:      runRemove,
+			complete: completeEntries,
+		},
+		{
+			name:    "move",
+			args:    "<entry>",
+			summary: "Move a PATH entry within its section and print the new PATH (or save it to a persistent store)",
+			flags: append(append([]cliFlag{
+				{name: "up", help: "Move one position up"},
+				{name: "down", help: "Move one position down"},
+			}, placementFlags...), append(modeFlags, varFlag, formatFlag)...),
+			run:      runMove,
+			complete: completeEntries,
+		},
+		{
+			name:    "clean",
+			summary: "Remove missing and duplicate entries and print the new PATH (or save it to a persistent store)",
+			flags: append([]cliFlag{
+				{name: "missing", help: "Remove entries that do not exist on disk"},
+				{name: "duplicates", help: "Remove duplicate entries (within a section in persistent stores)"},
+				{name: "dry-run", short: "n", help: "Only print what would be removed"},
+				{name: "check", help: "Like --dry-run, but exit with status 1 if anything would be removed"},
+			}, append(modeFlags, varFlag, formatFlag)...),
+			run: runClean,
+		},
+		{
+			name:    "shadows",
+			summary: "Report commands provided by more than one PATH entry, and which entry wins",
+			flags: append([]cliFlag{
+				{name: "format", short: "f", arg: "text|json", help: "Output format (default: text)"},
+			}, modeFlags...),
+			run: runShadows,
+		},
+		{
+			name:    "audit",
+			summary: "Report insecure PATH entries with their severity (exit 1 if any are found)",
+			flags: append([]cliFlag{
+				{name: "format", short: "f", arg: "text|json", help: "Output format (default: text)"},
+				{name: "fail-on", arg: "low|medium|high", help: "Lowest severity that makes the exit status 1 (default: low)"},
+			}, append(modeFlags, varFlag)...),
+			run: runAudit,
+		},
+		{
+			name:    "which",
+			args:    "<command>...",
+			summary: "Print the executable each command resolves to (exit 1 if one is not found)",
+			flags: append([]cliFlag{
+				{name: "all", short: "a", help: "Print every match in PATH order; the first one wins"},
+			}, modeFlags...),
+			run:      runWhich,
+			complete: completeCommands,
+		},
+		{
+			name:     "init",
+			args:     "<shell>",
+			summary:  "Print the shell integration function for bash, zsh, fish, nu, pwsh or cmd",
+			run:      runInit,
+			complete: func(*cliArgs) []string { return []string{"bash", "zsh", "fish", "nu", "pwsh", "cmd"} },
+		},
+		{
+			name:     "completion",
+			args:     "<shell>",
+			summary:  "Print the completion script for bash, zsh, fish or pwsh",
+			run:      runCompletion,
+			complete: func(*cliArgs) []string { return completionShells },
+		},
+		{
+			name:    "history",
+			summary: "List the backups written before each persisted change",
+			flags: []cliFlag{
+				{name: "format", short: "f", arg: "text|json", help: "Output format (default: text)"},
+			},
+			run: runHistory,
+		},
+		{
+			name:    "restore",
+			args:    "<id>",
+			summary: "Roll a store back to its state before a backup (see 'pathed history')",
+			flags: []cliFlag{
+				{name: "dry-run", short: "n", help: "Only print what would be restored"},
+			},
+			run:      runRestore,
+			complete: completeBackups,
+		},
+	}
+}
+
+// findCommand returns the subcommand with the given name, or nil
+func findCommand(name string) *cliCommand {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// findFlag returns the flag matching a long or short name, or nil
+func (c *cliCommand) findFlag(name string) *cliFlag {
+	for i := range c.flags {
+		if c.flags[i].name == name || (c.flags[i].short != "" && c.flags[i].short == name) {
+			return &c.flags[i]
+		}
+	}
+	return nil
+}
+
+// parseArgs parses args against the command's flag definitions.
+// Flags may appear anywhere; "--" ends flag parsing. Values are given as
+// "--flag value" or "--flag=value".
+func (c *cliCommand) parseArgs(args []string) (*cliArgs, error) {
+	a := &cliArgs{values: make(map[string]string)}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			a.positional = append(a.positional, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			a.positional = append(a.positional, arg)
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		value, hasValue := "", false
+		if eq := strings.IndexByte(name, '='); eq >= 0 {
+			name, value, hasValue = name[:eq], name[eq+1:], true
+		}
+		f := c.findFlag(name)
+		if f == nil {
+			return nil, fmt.Errorf("unknown option: %s", arg)
+		}
+		if f.arg == "" {
+			if hasValue {
+				return nil, fmt.Errorf("option --%s does not take a value", f.name)
+			}
+		} else if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("option --%s requires a value", f.name)
+			}
+			i++
+			value = args[i]
+		}
+		a.values[f.name] = value
+	}
+	return a, nil
+}
+
+// usage returns the help text for a subcommand
+func (c *cliCommand) usage() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "pathed %s - %s\n\nUSAGE:\n    pathed %s [OPTIONS]", c.name, c.summary, c.name)
+	if c.args != "" {
+		b.WriteString(" " + c.args)
+	}
+	b.WriteString("\n\nOPTIONS:\n")
+	for _, f := range append([]cliFlag{{name: "help", short: "h", help: "Show this help message"}}, c.flags...) {
+		names := "    --" + f.name
+		if f.short != "" {
+			names = "-" + f.short + ", --" + f.name
+		}
+		if f.arg != "" {
+			names += " <" + f.arg + ">"
+		}
+		fmt.Fprintf(&b, "    %-30s %s\n", names, f.help)
+	}
+	return b.String()
+}
+
+// runCommand parses arguments and runs a subcommand, returning the exit code
+func runCommand(c *cliCommand, args []string) int {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "-h" || arg == "--help" {
+			fmt.Print(c.usage())
+			return 0
+		}
+	}
+	a, err := c.parseArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\nUse 'pathed %s --help' for usage information.\n", err, c.name)
+		return 2
+	}
+	return c.run(a)
+}
+
+// cliError prints an error and returns the exit code for a failed command
+func cliError(err error) int {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	return 1
+}
+
+// loadCLIEditPaths loads the entries for a command that edits them, with the
+// backend set to print in the format chosen by --format
+func loadCLIEditPaths(a *cliArgs) ([]pathEntry, backend, error) {
+	paths, b, err := loadCLIPaths(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b, err = withFormat(b, a.get("format")); err != nil {
+		return nil, nil, err
+	}
+	return paths, b, nil
+}
+
+// loadCLIPaths loads the entries for a subcommand from the backend chosen by the mode
+// flags, of the variable chosen by --var
+func loadCLIPaths(a *cliArgs) ([]pathEntry, backend, error) {
+	v := pathVariable
+	if a.has("var") {
+		v = lookupVar(a.get("var"))
+	}
+	b, err := selectBackend(a.has("registry"), a.has("profile"), a.has("fish"), v)
+	if err != nil {
+		return nil, nil, err
+	}
+	paths, err := b.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading %s from %s: %w", v.name, b.Describe(), err)
+	}
+	return paths, b, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// auditFindingJSON is the JSON representation of a finding for "pathed audit"
+type auditFindingJSON struct {
+	Index    int    `json:"index"`
+	Path     string `json:"path"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// runAudit implements "pathed audit"
+func runAudit(a *cliArgs) int {
+	if len(a.positional) > 0 {
+		return cliError(fmt.Errorf("unexpected argument: %s", a.positional[0]))
+	}
+	failOn := severityLow
+	if a.has("fail-on") {
+		var err error
+		if failOn, err = parseSeverity(a.get("fail-on")); err != nil {
+			return cliError(err)
+		}
+	}
+	paths, b, err := loadCLIPaths(a)
+	if err != nil {
+		return cliError(err)
+	}
+	findings := auditPaths(paths, infoCache{}, b.Var())
+
+	switch format := a.get("format"); format {
+	case "", "text":
+		if len(findings) == 0 {
+			fmt.Fprintln(os.Stderr, "No issues found")
+		}
+		for _, f := range findings {
+			path := paths[f.index].path
+			if path == "" {
+				path = emptyEntryText(b.Var())
+			}
+			fmt.Printf("%-6s  %3d  %s  (%s)\n", strings.ToUpper(f.severity.String()), f.index+1, path, f.message)
+		}
+
+	case "json":
+		out := []auditFindingJSON{}
+		for _, f := range findings {
+			out = append(out, auditFindingJSON{Index: f.index + 1, Path: paths[f.index].path, Severity: f.severity.String(), Message: f.message})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return cliError(err)
+		}
+
+	default:
+		return cliError(fmt.Errorf("unknown format %q (expected text or json)", format))
+	}
+
+	if failsAudit(findings, failOn) {
+		return 1
+	}
+	return 0
+}
+
+// failsAudit reports whether any finding is at least as severe as failOn
+func failsAudit(findings []auditFinding, failOn severity) bool {
+	for _, f := range findings {
+		if f.severity >= failOn {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runHistory implements "pathed history"
+func runHistory(a *cliArgs) int {
+	if len(a.positional) > 0 {
+		return cliError(fmt.Errorf("unexpected argument: %s", a.positional[0]))
+	}
+	backups, err := loadBackups()
+	if err != nil {
+		return cliError(err)
+	}
+
+	switch format := a.get("format"); format {
+	case "", "text":
+		if len(backups) == 0 {
+			fmt.Fprintln(os.Stderr, "No backups yet - one is written every time changes are persisted")
+		}
+		for _, bk := range backups {
+			var changes []string
+			for _, s := range bk.Sections {
+				added, removed := s.countChanges()
+				name := s.Name
+				if name == "" {
+					name = "path"
+				}
+				changes = append(changes, fmt.Sprintf("%s +%d -%d", name, added, removed))
+			}
+			store := bk.Store
+			if bk.Var != "" {
+				store += " " + bk.Var
+			}
+			fmt.Printf("%s  %s  %-10s  %s  [%s]\n", bk.ID, bk.Time.Local().Format("2006-01-02 15:04:05"), bk.User, store, strings.Join(changes, ", "))
+		}
+
+	case "json":
+		if backups == nil {
+			backups = []*backup{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(backups); err != nil {
+			return cliError(err)
+		}
+
+	default:
+		return cliError(fmt.Errorf("unknown format %q (expected text or json)", format))
+	}
+	return 0
+}
+
+// runRestore implements "pathed restore"
+func runRestore(a *cliArgs) int {
+	if len(a.positional) != 1 {
+		return cliError(fmt.Errorf("expected exactly one backup id"))
+	}
+	bk, err := findBackup(a.positional[0])
+	if err != nil {
+		return cliError(err)
+	}
+	b, err := findBackend(bk.Store, bk.variable())
+	if err != nil {
+		return cliError(err)
+	}
+	current, err := b.Load()
+	if err != nil {
+		return cliError(err)
+	}
+	paths := restoreEntries(current, bk)
+
+	// Show what will change, section by section
+	for _, s := range bk.Sections {
+		name := s.Name
+		if name == "" {
+			name = "path"
+		}
+		fmt.Printf("%s %s %s:\n", b.Describe(), b.Var().name, name)
+		for _, p := range sectionPaths(current, s.Name) {
+			fmt.Printf("  - %s\n", p)
+		}
+		for _, p := range s.Previous {
+			fmt.Printf("  + %s\n", p)
+		}
+	}
+
+	if a.has("dry-run") {
+		return 0
+	}
+	// Restoring is itself persisted with a backup, so it can be rolled back too
+	if err := saveWithBackup(b, paths, ""); err != nil {
+		return cliError(err)
+	}
+	fmt.Fprintf(os.Stderr, "Restored %s in %s to its state before %s\n", b.Var().name, b.Describe(), bk.ID)
+	return 0
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// runClean implements "pathed clean"
+func runClean(a *cliArgs) int {
+	if len(a.positional) > 0 {
+		return cliError(fmt.Errorf("unexpected argument: %s", a.positional[0]))
+	}
+	paths, b, err := loadCLIEditPaths(a)
+	if err != nil {
+		return cliError(err)
+	}
+
+	// Without --missing or --duplicates, clean both (same as 'c' in the TUI)
+	missing, duplicates := a.has("missing"), a.has("duplicates")
+	if !missing && !duplicates {
+		missing, duplicates = true, true
+	}
+	check := a.has("check")
+	dryRun := a.has("dry-run") || check
+
+	// The diff goes to stdout unless stdout carries the PATH string for shell capture
+	var diff io.Writer = os.Stdout
+	if !dryRun && !persists(b) {
+		diff = os.Stderr
+	}
+
+	cleaned := 0
+	for i, reason := range cleanReasons(b, paths, missing, duplicates) {
+		if reason == "" {
+			continue
+		}
+		cleaned++
+		paths[i].deleted = true
+		paths[i].modified = true
+		if persists(b) {
+			fmt.Fprintf(diff, "- %3d  %-6s  %s  (%s)\n", i+1, paths[i].source, paths[i].path, reason)
+		} else {
+			fmt.Fprintf(diff, "- %3d  %s  (%s)\n", i+1, paths[i].path, reason)
+		}
+	}
+
+	if dryRun {
+		if check && cleaned > 0 {
+			fmt.Fprintf(os.Stderr, "%d PATH entries would be removed\n", cleaned)
+			return 1
+		}
+		return 0
+	}
+	return applyCLIChanges(paths, b)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCleanReasons(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("PATH", "/usr/bin")
+	entry := func(path, source string, v pathVar) pathEntry {
+		return pathEntry{path: path, source: source, exists: v.exists(path)}
+	}
+	manpath := lookupVar("MANPATH")
+	env := []pathEntry{
+		entry(dir, "", pathVariable),
+		entry("/pathed/missing", "", pathVariable),
+		entry("~", "", pathVariable), // same directory as the first, once expanded
+		entry("", "", pathVariable),  // the current directory, by accident
+		entry("/pathed/missing", "", pathVariable),
+	}
+	tests := []struct {
+		name                string
+		b                   backend
+		paths               []pathEntry
+		missing, duplicates bool
+		want                []string
+	}{
+		{
+			name: "both", b: envBackend{v: pathVariable}, paths: env, missing: true, duplicates: true,
+			want: []string{"", "missing", "duplicate", "missing", "missing"},
+		},
+		{
+			name: "missing only", b: envBackend{v: pathVariable}, paths: env, missing: true,
+			want: []string{"", "missing", "", "missing", "missing"},
+		},
+		{
+			name: "duplicates only", b: envBackend{v: pathVariable}, paths: env, duplicates: true,
+			want: []string{"", "", "duplicate", "", "duplicate"},
+		},
+		{
+			name: "meaningful empty segments", b: envBackend{v: manpath}, missing: true, duplicates: true,
+			paths: []pathEntry{entry("", "", manpath), entry(dir, "", manpath), entry("", "", manpath)},
+			want:  []string{"", "", "duplicate"},
+		},
+		{
+			name: "duplicates within a section", b: registryFixture(), missing: true, duplicates: true,
+			paths: []pathEntry{entry(dir, "system", pathVariable), entry(dir, "user", pathVariable), entry(dir, "user", pathVariable)},
+			want:  []string{"", "", "duplicate"},
+		},
+		{
+			name: "inherited entries are kept", b: profileBackend{v: pathVariable}, missing: true, duplicates: true,
+			paths: []pathEntry{entry(dir, "user", pathVariable), entry("/pathed/missing", "user", pathVariable), entry(dir, "system", pathVariable), entry("/pathed/missing", "system", pathVariable)},
+			want:  []string{"", "missing", "", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanReasons(tt.b, tt.paths, tt.missing, tt.duplicates); !slices.Equal(got, tt.want) {
+				t.Errorf("cleanReasons() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// registryFixture returns an empty test registry backend
+func registryFixture() backend {
+	b, _ := newTestRegistry(nil, nil)
+	return b
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// placementFlags are the options that choose where an added or moved entry goes
+var placementFlags = []cliFlag{
+	{name: "prepend", help: "Place at the start of the section"},
+	{name: "append", help: "Place at the end of the section (default)"},
+	{name: "before", arg: "entry", help: "Place before an entry (path or index from 'pathed list')", complete: completeEntries},
+	{name: "after", arg: "entry", help: "Place after an entry (path or index from 'pathed list')", complete: completeEntries},
+}
+
+// sourceFlag selects the registry section an entry belongs to
+var sourceFlag = cliFlag{name: "source", short: "s", arg: "user|system", help: "Section of a persistent store (default: user)"}
+
+// placement describes where an entry should be placed, parsed from placement flags
+type placement struct {
+	kind   string // "prepend", "append", "before", "after", "up" or "down"
+	ref    string // reference entry for "before" and "after"
+	refIdx int    // index of the reference entry, set by resolveRef
+}
+
+// parsePlacement returns the single placement option given, defaulting to "append"
+func parsePlacement(a *cliArgs, kinds ...string) (placement, error) {
+	p := placement{kind: "append"}
+	count := 0
+	for _, kind := range kinds {
+		if a.has(kind) {
+			p = placement{kind: kind, ref: a.get(kind)}
+			count++
+		}
+	}
+	if count > 1 {
+		return p, fmt.Errorf("only one placement option may be given")
+	}
+	return p, nil
+}
+
+// resolveRef resolves the reference entry of a "before" or "after" placement
+func (p *placement) resolveRef(paths []pathEntry) error {
+	if p.kind != "before" && p.kind != "after" {
+		return nil
+	}
+	idx, err := resolveEntry(paths, p.ref)
+	p.refIdx = idx
+	return err
+}
+
+// parseSource validates --source against the sections the backend persists
+func parseSource(a *cliArgs, b backend) (string, error) {
+	if !a.has("source") {
+		return "", nil
+	}
+	source := a.get("source")
+	if !persists(b) || !hasSection(b, source) {
+		return "", fmt.Errorf("the %s has no %q section", b.Describe(), source)
+	}
+	return source, nil
+}
+
+// resolveEntry finds the entry referenced by ref: a 1-based index as printed by
+// "pathed list", or a path (compared after expansion and normalization). The first
+// match wins.
+func resolveEntry(paths []pathEntry, ref string) (int, error) {
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(paths) {
+			return 0, fmt.Errorf("index %d out of range (1-%d)", n, len(paths))
+		}
+		return n - 1, nil
+	}
+	key := pathKey(ref)
+	for i, p := range paths {
+		if !p.deleted && pathKey(p.path) == key {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no PATH entry matches %q", ref)
+}
+
+// checkEditable returns an error if p is inherited from the environment, so b
+// doesn't save changes to it
+func checkEditable(b backend, p pathEntry) error {
+	if inherited(b, p) {
+		return fmt.Errorf("%s is inherited from the environment and is not saved to %s", p.path, b.Describe())
+	}
+	return nil
+}
+
+// placementIndex returns the index at which an entry of the given source should be
+// inserted. Reference entries for --before/--after must be in the same section.
+func placementIndex(paths []pathEntry, source string, p placement) (int, error) {
+	start, end := sectionBounds(paths, source)
+	switch p.kind {
+	case "prepend":
+		return start, nil
+	case "before", "after":
+		idx := p.refIdx
+		if paths[idx].source != source {
+			return 0, fmt.Errorf("%q is in the %s section, not %s", paths[idx].path, paths[idx].source, source)
+		}
+		if p.kind == "after" {
+			idx++
+		}
+		return idx, nil
+	default:
+		return end, nil
+	}
+}
+
+// applyCLIChanges outputs or persists edited entries the same way the TUI does on quit:
+// env mode prints the PATH string for shell capture, persistent stores save in place
+func applyCLIChanges(paths []pathEntry, b backend) int {
+	if err := persistPaths(b, paths, ""); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving to %s: %v\n", b.Describe(), err)
+		return 1
+	}
+	printPersistNotice(b)
+	return 0
+}
+
+// runAdd implements "pathed add"
+func runAdd(a *cliArgs) int {
+	if len(a.positional) != 1 {
+		return cliError(fmt.Errorf("expected exactly one path"))
+	}
+	path := a.positional[0]
+	paths, b, err := loadCLIEditPaths(a)
+	if err != nil {
+		return cliError(err)
+	}
+	source, err := parseSource(a, b)
+	if err != nil {
+		return cliError(err)
+	}
+	pl, err := parsePlacement(a, "prepend", "append", "before", "after")
+	if err == nil {
+		err = pl.resolveRef(paths)
+	}
+	if err != nil {
+		return cliError(err)
+	}
+
+	if source == "" {
+		// Default to the reference entry's section, otherwise the user section
+		source = defaultSource(b)
+		if pl.kind == "before" || pl.kind == "after" {
+			if err := checkEditable(b, paths[pl.refIdx]); err != nil {
+				return cliError(err)
+			}
+			source = paths[pl.refIdx].source
+		}
+	}
+
+	if a.has("if-missing") {
+		key := pathKey(path)
+		for _, p := range paths {
+			if p.source == source && pathKey(p.path) == key {
+				return applyCLIChanges(paths, b)
+			}
+		}
+	}
+
+	idx, err := placementIndex(paths, source, pl)
+	if err != nil {
+		return cliError(err)
+	}
+	paths = insertPathEntryAt(paths, idx, pathEntry{
+		path:     path,
+		source:   source,
+		modified: true,
+		added:    true,
+		exists:   b.Var().exists(path),
+	})
+	return applyCLIChanges(paths, b)
+}
+
+// runRemove implements "pathed remove"
+func runRemove(a *cliArgs) int {
+	if len(a.positional) == 0 {
+		return cliError(fmt.Errorf("expected at least one entry"))
+	}
+	paths, b, err := loadCLIEditPaths(a)
+	if err != nil {
+		return cliError(err)
+	}
+	source, err := parseSource(a, b)
+	if err != nil {
+		return cliError(err)
+	}
+
+	for _, ref := range a.positional {
+		found := false
+		if _, err := strconv.Atoi(ref); err == nil {
+			// Index: remove exactly that entry
+			idx, err := resolveEntry(paths, ref)
+			if err == nil {
+				err = checkEditable(b, paths[idx])
+			}
+			if err != nil {
+				return cliError(err)
+			}
+			if source == "" || paths[idx].source == source {
+				paths[idx].deleted = true
+				found = true
+			}
+		} else {
+			// Path: remove every occurrence (within the section, if given), except
+			// inherited ones
+			key := pathKey(ref)
+			var inheritedMatch error
+			for i := range paths {
+				if pathKey(paths[i].path) != key || (source != "" && paths[i].source != source) {
+					continue
+				}
+				if err := checkEditable(b, paths[i]); err != nil {
+					inheritedMatch = err
+					continue
+				}
+				paths[i].deleted = true
+				found = true
+			}
+			if !found && inheritedMatch != nil {
+				return cliError(inheritedMatch)
+			}
+		}
+		if !found && !a.has("if-exists") {
+			return cliError(fmt.Errorf("no PATH entry matches %q", ref))
+		}
+	}
+	return applyCLIChanges(paths, b)
+}
+
+// runMove implements "pathed move"
+func runMove(a *cliArgs) int {
+	if len(a.positional) != 1 {
+		return cliError(fmt.Errorf("expected exactly one entry"))
+	}
+	paths, b, err := loadCLIEditPaths(a)
+	if err != nil {
+		return cliError(err)
+	}
+	from, err := resolveEntry(paths, a.positional[0])
+	if err == nil {
+		err = checkEditable(b, paths[from])
+	}
+	if err != nil {
+		return cliError(err)
+	}
+	pl, err := parsePlacement(a, "up", "down", "prepend", "append", "before", "after")
+	if err == nil {
+		err = pl.resolveRef(paths)
+	}
+	if err != nil {
+		return cliError(err)
+	}
+	if !a.has(pl.kind) {
+		return cliError(fmt.Errorf("expected a placement option (--up, --down, --prepend, --append, --before or --after)"))
+	}
+	source := paths[from].source
+
+	// Like J/K in the TUI, moves stay within the entry's section
+	switch pl.kind {
+	case "up", "down":
+		neighbour := from - 1
+		if pl.kind == "down" {
+			neighbour = from + 1
+		}
+		if neighbour < 0 || neighbour >= len(paths) || paths[neighbour].source != source {
+			return cliError(fmt.Errorf("cannot move %s: entry is already at the edge of its section", pl.kind))
+		}
+		paths[from], paths[neighbour] = paths[neighbour], paths[from]
+		paths[neighbour].modified = true
+		return applyCLIChanges(paths, b)
+	case "before", "after":
+		if pl.refIdx == from {
+			return cliError(fmt.Errorf("cannot move an entry relative to itself"))
+		}
+		if pl.refIdx > from {
+			pl.refIdx-- // account for the entry being taken out of the list
+		}
+	}
+
+	// Compute the target position with the entry taken out of the list
+	rest := append(append([]pathEntry{}, paths[:from]...), paths[from+1:]...)
+	to, err := placementIndex(rest, source, pl)
+	if err != nil {
+		return cliError(err)
+	}
+	paths = movePathEntry(paths, from, to)
+	return applyCLIChanges(paths, b)
+}
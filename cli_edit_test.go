@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+// editFixture is a store with system and user sections, as "pathed list" numbers them
+func editFixture() []pathEntry {
+	return []pathEntry{
+		{path: "/s1", source: "system"},
+		{path: "$HOME/bin", source: "system"},
+		{path: "/s1", source: "system"},
+		{path: "/u1", source: "user"},
+		{path: "/gone", source: "user", deleted: true},
+		{path: "/u2", source: "user"},
+	}
+}
+
+func TestResolveEntry(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+	tests := []struct {
+		ref     string
+		want    int
+		wantErr bool
+	}{
+		{ref: "1", want: 0},
+		{ref: "6", want: 5},
+		{ref: "0", wantErr: true},
+		{ref: "7", wantErr: true},
+		{ref: "-1", wantErr: true},
+		{ref: "/s1", want: 0},          // the first match wins
+		{ref: "/home/me/bin", want: 1}, // compared after expansion
+		{ref: "~/bin", want: 1},
+		{ref: "/u2", want: 5},
+		{ref: "/gone", wantErr: true}, // deleted entries don't match
+		{ref: "/nope", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := resolveEntry(editFixture(), tt.ref)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("resolveEntry(%q) = %d, %v, want %d (error %v)", tt.ref, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPlacementIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		p       placement
+		want    int
+		wantErr bool
+	}{
+		{name: "append to system", source: "system", p: placement{kind: "append"}, want: 3},
+		{name: "append to user", source: "user", p: placement{kind: "append"}, want: 6},
+		{name: "prepend to system", source: "system", p: placement{kind: "prepend"}, want: 0},
+		{name: "prepend to user", source: "user", p: placement{kind: "prepend"}, want: 3},
+		{name: "before an entry", source: "user", p: placement{kind: "before", refIdx: 5}, want: 5},
+		{name: "after an entry", source: "user", p: placement{kind: "after", refIdx: 3}, want: 4},
+		{name: "after the last entry", source: "system", p: placement{kind: "after", refIdx: 2}, want: 3},
+		{name: "reference in the other section", source: "user", p: placement{kind: "before", refIdx: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := placementIndex(editFixture(), tt.source, tt.p)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("%s: placementIndex() = %d, %v, want %d (error %v)", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParsePlacement(t *testing.T) {
+	a := &cliArgs{values: map[string]string{"after": "/u1"}}
+	p, err := parsePlacement(a, "prepend", "append", "before", "after")
+	if err != nil || p.kind != "after" || p.ref != "/u1" {
+		t.Errorf("parsePlacement(--after /u1) = %+v, %v", p, err)
+	}
+	if err := p.resolveRef(editFixture()); err != nil || p.refIdx != 3 {
+		t.Errorf("resolveRef() = %v, refIdx %d, want 3", err, p.refIdx)
+	}
+	a.values["prepend"] = ""
+	if _, err := parsePlacement(a, "prepend", "append", "before", "after"); err == nil {
+		t.Error("parsePlacement(--after --prepend) = nil, want an error")
+	}
+}
+
+func TestCheckEditable(t *testing.T) {
+	profile := profileBackend{v: pathVariable}
+	if err := checkEditable(profile, pathEntry{path: "/u1", source: "user"}); err != nil {
+		t.Errorf("checkEditable(profile user entry) = %v, want nil", err)
+	}
+	if err := checkEditable(profile, pathEntry{path: "/usr/bin", source: "system"}); err == nil {
+		t.Error("checkEditable(profile system entry) = nil, want an error")
+	}
+	registry, _ := newTestRegistry(nil, nil)
+	if err := checkEditable(registry, pathEntry{path: "/s1", source: "system"}); err != nil {
+		t.Errorf("checkEditable(registry system entry) = %v, want nil", err)
+	}
+	if err := checkEditable(envBackend{v: pathVariable}, pathEntry{path: "/a"}); err != nil {
+		t.Errorf("checkEditable(env entry) = %v, want nil", err)
+	}
+}
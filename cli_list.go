@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// listEntryJSON is the JSON representation of a path entry for "pathed list"
+type listEntryJSON struct {
+	Index     int    `json:"index"`
+	Path      string `json:"path"`
+	Expanded  string `json:"expanded"`
+	Source    string `json:"source"`
+	Exists    bool   `json:"exists"`
+	Duplicate bool   `json:"duplicate"`
+}
+
+// runList implements "pathed list"
+func runList(a *cliArgs) int {
+	if len(a.positional) > 0 {
+		return cliError(fmt.Errorf("unexpected argument: %s", a.positional[0]))
+	}
+	paths, b, err := loadCLIPaths(a)
+	if err != nil {
+		return cliError(err)
+	}
+	duplicates := findDuplicates(paths)
+
+	switch format := a.get("format"); format {
+	case "", "text":
+		for i, p := range paths {
+			var notes []string
+			if !p.exists {
+				notes = append(notes, "missing")
+			}
+			if duplicates[i] {
+				notes = append(notes, "duplicate")
+			}
+			line := fmt.Sprintf("%3d  ", i+1)
+			if persists(b) {
+				line += fmt.Sprintf("%-6s  ", p.source)
+			}
+			line += displayPath(p, true, b.Var())
+			if len(notes) > 0 {
+				line += "  (" + strings.Join(notes, ", ") + ")"
+			}
+			fmt.Println(line)
+		}
+
+	case "json":
+		out := make([]listEntryJSON, len(paths))
+		for i, p := range paths {
+			out[i] = listEntryJSON{Index: i + 1, Path: p.path, Expanded: expandPath(p.path), Source: p.source, Exists: p.exists, Duplicate: duplicates[i]}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return cliError(err)
+		}
+
+	case "tsv":
+		fmt.Println("index\tsource\texists\tduplicate\tpath\texpanded")
+		for i, p := range paths {
+			fmt.Printf("%d\t%s\t%t\t%t\t%s\t%s\n", i+1, p.source, p.exists, duplicates[i], p.path, expandPath(p.path))
+		}
+
+	default:
+		return cliError(fmt.Errorf("unknown format %q (expected text, json or tsv)", format))
+	}
+	return 0
+}
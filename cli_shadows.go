@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// shadowsEntryJSON is the JSON representation of one entry in "pathed shadows"
+type shadowsEntryJSON struct {
+	Index       int                  `json:"index"`
+	Path        string               `json:"path"`
+	Executables int                  `json:"executables"`
+	Shadows     []shadowConflictJSON `json:"shadows"`
+	ShadowedBy  []shadowConflictJSON `json:"shadowedBy"`
+}
+
+// shadowConflictJSON is a command and the 1-based indices of the other entries involved
+type shadowConflictJSON struct {
+	Command string `json:"command"`
+	Entries []int  `json:"entries"`
+}
+
+// conflictsJSON converts conflicts to their JSON form
+func conflictsJSON(conflicts []shadowConflict) []shadowConflictJSON {
+	out := []shadowConflictJSON{}
+	for _, c := range conflicts {
+		entries := make([]int, len(c.entries))
+		for i, idx := range c.entries {
+			entries[i] = idx + 1
+		}
+		out = append(out, shadowConflictJSON{Command: c.command, Entries: entries})
+	}
+	return out
+}
+
+// runShadows implements "pathed shadows"
+func runShadows(a *cliArgs) int {
+	if len(a.positional) > 0 {
+		return cliError(fmt.Errorf("unexpected argument: %s", a.positional[0]))
+	}
+	paths, _, err := loadCLIPaths(a)
+	if err != nil {
+		return cliError(err)
+	}
+	analysis := analyzeShadows(paths, execCache{})
+
+	switch format := a.get("format"); format {
+	case "", "text":
+		found := false
+		for i, p := range paths {
+			shadows, shadowedBy := analysis.shadows(i), analysis.shadowedBy(i)
+			if len(shadows) == 0 && len(shadowedBy) == 0 {
+				continue
+			}
+			found = true
+			fmt.Printf("%3d  %s  (%d executables)\n", i+1, p.path, len(analysis.execs[i]))
+			for _, c := range shadows {
+				fmt.Printf("       %-20s hides %s\n", c.command, describeEntries(paths, c.entries))
+			}
+			for _, c := range shadowedBy {
+				fmt.Printf("       %-20s hidden by %s\n", c.command, describeEntries(paths, c.entries))
+			}
+		}
+		if !found {
+			fmt.Fprintln(os.Stderr, "No command is provided by more than one PATH entry")
+		}
+
+	case "json":
+		out := []shadowsEntryJSON{}
+		for i, p := range paths {
+			if analysis.execs[i] == nil {
+				continue
+			}
+			out = append(out, shadowsEntryJSON{
+				Index:       i + 1,
+				Path:        p.path,
+				Executables: len(analysis.execs[i]),
+				Shadows:     conflictsJSON(analysis.shadows(i)),
+				ShadowedBy:  conflictsJSON(analysis.shadowedBy(i)),
+			})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return cliError(err)
+		}
+
+	default:
+		return cliError(fmt.Errorf("unknown format %q (expected text or json)", format))
+	}
+	return 0
+}
+
+// describeEntries formats entries as "index path" pairs for reports
+func describeEntries(paths []pathEntry, indices []int) string {
+	parts := make([]string, len(indices))
+	for i, idx := range indices {
+		parts[i] = fmt.Sprintf("%d %s", idx+1, paths[idx].path)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runWhich implements "pathed which"
+func runWhich(a *cliArgs) int {
+	if len(a.positional) == 0 {
+		return cliError(fmt.Errorf("expected at least one command"))
+	}
+	paths, _, err := loadCLIPaths(a)
+	if err != nil {
+		return cliError(err)
+	}
+	analysis := analyzeShadows(paths, execCache{})
+
+	status := 0
+	for _, command := range a.positional {
+		matches := analysis.which(command)
+		if len(matches) == 0 {
+			fmt.Fprintf(os.Stderr, "%s: not found\n", command)
+			status = 1
+			continue
+		}
+		if !a.has("all") {
+			matches = matches[:1]
+		}
+		for _, match := range matches {
+			fmt.Println(filepath.Join(expandPath(paths[match.index].path), match.file))
+		}
+	}
+	return status
+}
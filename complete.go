@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Shell completion: the scripts printed by "pathed completion" pass the words of the
+// command line to the hidden "pathed __complete" command, which answers from the
+// command table, so new commands and flags complete without changing the scripts.
+// Each candidate is printed on its own line as value, tab, description.
+
+// completeCommandName is the hidden command the completion scripts call
+const completeCommandName = "__complete"
+
+// completionShells lists the shells "pathed completion" supports
+var completionShells = []string{"bash", "zsh", "fish", "pwsh"}
+
+// rootCommand describes the options of pathed itself, which start the TUI
+var rootCommand = &cliCommand{
+	name: "pathed",
+	flags: append(append([]cliFlag{
+		{name: "help", short: "h", help: "Show this help message"},
+		{name: "version", short: "v", help: "Show version"},
+	}, modeFlags...), varFlag, formatFlag),
+}
+
+// completions returns the candidates for the last of args, the word being completed;
+// the others are the words before it, after "pathed"
+func completions(args []string) []string {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	cur := args[len(args)-1]
+	if cur == `""` {
+		cur = "" // PowerShell passes an empty word quoted
+	}
+	c, words := rootCommand, args[:len(args)-1]
+	if len(words) > 0 {
+		if cmd := findCommand(words[0]); cmd != nil {
+			c, words = cmd, words[1:]
+		}
+	}
+	a, err := c.parseArgs(words)
+	if err != nil {
+		a = &cliArgs{values: make(map[string]string)}
+	}
+
+	// The value of a flag, as the next word or after "="
+	if len(words) > 0 {
+		if f := c.findFlag(strings.TrimLeft(words[len(words)-1], "-")); f != nil && f.arg != "" && strings.HasPrefix(words[len(words)-1], "-") {
+			return matching(f.values(a), cur, "")
+		}
+	}
+	if name, value, ok := strings.Cut(cur, "="); ok && strings.HasPrefix(name, "-") {
+		if f := c.findFlag(strings.TrimLeft(name, "-")); f != nil && f.arg != "" {
+			return matching(f.values(a), value, name+"=")
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(cur, "-") {
+		flags := c.flags
+		if c != rootCommand {
+			flags = append([]cliFlag{{name: "help", short: "h", help: "Show this help message"}}, flags...)
+		}
+		var out []string
+		for _, f := range flags {
+			if strings.HasPrefix("--"+f.name, cur) {
+				out = append(out, "--"+f.name+"\t"+f.help)
+			}
+		}
+		return out
+	}
+	if c == rootCommand {
+		var out []string
+		for _, cmd := range commands {
+			if strings.HasPrefix(cmd.name, cur) {
+				out = append(out, cmd.name+"\t"+cmd.summary)
+			}
+		}
+		return out
+	}
+	if c.complete != nil {
+		return matching(c.complete(a), cur, "")
+	}
+	return nil
+}
+
+// values returns the candidates for a flag's value: from its complete function, or
+// the alternatives listed in its placeholder, like "text|json"
+func (f *cliFlag) values(a *cliArgs) []string {
+	if f.complete != nil {
+		return f.complete(a)
+	}
+	if strings.Contains(f.arg, "|") {
+		return strings.Split(f.arg, "|")
+	}
+	return nil
+}
+
+// matching returns the non-empty values starting with prefix, each with an empty
+// description, prepending lead to each
+func matching(values []string, prefix, lead string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" && strings.HasPrefix(v, prefix) {
+			out = append(out, lead+v+"\t")
+		}
+	}
+	return out
+}
+
+// completeEntries returns the entries of the store and variable chosen by the flags
+// given so far
+func completeEntries(a *cliArgs) []string {
+	paths, _, err := loadCLIPaths(a)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, p := range paths {
+		out = append(out, p.path)
+	}
+	return out
+}
+
+// completeCommands returns the commands found in the PATH directories
+func completeCommands(*cliArgs) []string {
+	var out []string
+	for name := range analyzeShadows(loadPathsFromEnv(pathVariable), execCache{}).providers {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// completeBackups returns the IDs of the backups, newest first
+func completeBackups(*cliArgs) []string {
+	backups, _ := loadBackups()
+	var out []string
+	for i := len(backups) - 1; i >= 0; i-- {
+		out = append(out, backups[i].ID)
+	}
+	return out
+}
+
+// runComplete implements the hidden "pathed __complete" command
+func runComplete(args []string) int {
+	for _, c := range completions(args) {
+		fmt.Println(c)
+	}
+	return 0
+}
+
+// completionScripts holds the completion script for each shell
+var completionScripts = map[string]string{
+	"bash": `# pathed completion for bash: add to ~/.bashrc
+#   eval "$(pathed completion bash)"
+_pathed() {
+    local line
+    COMPREPLY=()
+    while IFS= read -r line; do
+        COMPREPLY+=("$(printf '%q' "${line%%$'\t'*}")")
+    done < <(command pathed __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)
+}
+complete -o default -F _pathed pathed
+`,
+	"zsh": `# pathed completion for zsh: add to ~/.zshrc (after compinit)
+#   eval "$(pathed completion zsh)"
+_pathed() {
+    local -a candidates
+    local line value
+    for line in "${(@f)$(command pathed __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -z $line ]] && continue
+        value=${line%%$'\t'*}
+        candidates+=("${value//:/\\:}:${line#*$'\t'}")
+    done
+    if (( ${#candidates} )); then
+        _describe pathed candidates
+    else
+        _files
+    fi
+}
+compdef _pathed pathed
+`,
+	"fish": `# pathed completion for fish: add to ~/.config/fish/config.fish
+#   pathed completion fish | source
+function __pathed_complete
+    set -l candidates (command pathed __complete (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)
+    if test (count $candidates) -eq 0
+        __fish_complete_path (commandline -ct)
+    else
+        printf '%s\n' $candidates
+    end
+end
+complete -c pathed -f -a '(__pathed_complete)'
+`,
+	"pwsh": `# pathed completion for PowerShell: add to $PROFILE
+#   Invoke-Expression (& pathed completion pwsh | Out-String)
+Register-ArgumentCompleter -Native -CommandName pathed, pathed.exe -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') { $words += '""' }
+    $exe = Get-Command pathed -CommandType Application | Select-Object -First 1
+    & $exe __complete @words 2>$null | ForEach-Object {
+        $value, $description = $_ -split "` + "`t" + `", 2
+        if (-not $description) { $description = $value }
+        [System.Management.Automation.CompletionResult]::new($value, $value, 'ParameterValue', $description)
+    }
+}
+`,
+}
+
+// runCompletion implements "pathed completion"
+func runCompletion(a *cliArgs) int {
+	if len(a.positional) != 1 {
+		return cliError(fmt.Errorf("expected exactly one shell (%s)", strings.Join(completionShells, ", ")))
+	}
+	script, ok := completionScripts[a.positional[0]]
+	if !ok {
+		return cliError(fmt.Errorf("unknown shell %q (expected %s)", a.positional[0], strings.Join(completionShells, ", ")))
+	}
+	fmt.Print(script)
+	return 0
+}
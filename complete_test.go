@@ -0,0 +1,84 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// completionValues returns the values of completion candidates, without descriptions
+func completionValues(candidates []string) []string {
+	var out []string
+	for _, c := range candidates {
+		value, _, _ := strings.Cut(c, "\t")
+		out = append(out, value)
+	}
+	return out
+}
+
+func TestCompletions(t *testing.T) {
+	bin := t.TempDir()
+	for _, name := range []string{"pathed-tool", "pathed-other"} {
+		if err := os.WriteFile(filepath.Join(bin, name), nil, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin+":/pathed/missing")
+	t.Setenv("MY_TEST_PATH", "/x")
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"commands", []string{"re"}, []string{"remove", "restore"}},
+		{"root flags", []string{"--v"}, []string{"--version", "--var"}},
+		{"command flags", []string{"list", "--f"}, []string{"--format", "--fish"}},
+		{"help on commands", []string{"audit", "--h"}, []string{"--help"}},
+		{"flag values from the placeholder", []string{"audit", "--fail-on", "m"}, []string{"medium"}},
+		{"flag values after =", []string{"list", "--format=t"}, []string{"--format=text", "--format=tsv"}},
+		{"unknown flag after =", []string{"list", "--nope="}, nil},
+		{"output formats", []string{"--format", "p"}, []string{"posix", "pwsh"}},
+		{"variables", []string{"--var", "MY_TEST"}, []string{"MY_TEST_PATH"}},
+		{"entries", []string{"remove", "/pathed"}, []string{"/pathed/missing"}},
+		{"entries of another variable", []string{"remove", "--var", "MY_TEST_PATH", ""}, []string{"/x"}},
+		{"placement reference", []string{"add", "/new", "--before", bin[:4]}, []string{bin}},
+		{"commands on PATH", []string{"which", "pathed-"}, []string{"pathed-other", "pathed-tool"}},
+		{"PowerShell's empty word", []string{"which", `""`}, []string{"pathed-other", "pathed-tool"}},
+		{"no positional completion", []string{"list", ""}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := completionValues(completions(tt.args)); !slices.Equal(got, tt.want) {
+				t.Errorf("completions(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+	if got := completionValues(completions(nil)); !slices.Contains(got, "list") || !slices.Contains(got, "which") {
+		t.Errorf("completions() = %q, want every command", got)
+	}
+}
+
+func TestCompleteBackups(t *testing.T) {
+	t.Setenv("PATHED_STATE_DIR", t.TempDir())
+	b, _ := newTestRegistry(nil, []string{"/u1"})
+	var ids []string
+	for _, p := range []string{"/u2", "/u3"} {
+		paths, _ := b.Load()
+		paths = insertPathEntry(paths, pathEntry{path: p, source: "user", added: true})
+		if err := saveWithBackup(b, paths, ""); err != nil {
+			t.Fatal(err)
+		}
+		backups, _ := loadBackups()
+		ids = append(ids, backups[len(backups)-1].ID)
+	}
+	// Newest first
+	want := []string{ids[1], ids[0]}
+	if got := completionValues(completions([]string{"restore", ""})); !slices.Equal(got, want) {
+		t.Errorf("completions(restore) = %q, want %q", got, want)
+	}
+}
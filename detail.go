@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// dirInfo is metadata about a PATH entry's directory, shown in the detail pane
+type dirInfo struct {
+	expanded string      // path after variable expansion
+	link     string      // symlink target, if the entry itself is a symlink
+	resolved string      // path with all symlinks resolved
+	owner    string      // owning user, or "" if unknown
+	mode     fs.FileMode // type and permission bits
+	writable bool        // true if the current user can create files in it
+	// worldWritable is true if every user can create files in it
+	worldWritable bool
+	// otherOwner is true if it is owned by a user other than the current one and the system
+	otherOwner bool
+	err        error // why the directory could not be inspected
+}
+
+// readDirInfo inspects the directory of a PATH entry
+func readDirInfo(path string) dirInfo {
+	info := dirInfo{expanded: expandPath(path)}
+	if fi, err := os.Lstat(info.expanded); err == nil && fi.Mode()&fs.ModeSymlink != 0 {
+		info.link, _ = os.Readlink(info.expanded)
+	}
+	if resolved, err := filepath.EvalSymlinks(info.expanded); err == nil {
+		info.resolved = resolved
+	}
+	fi, err := os.Stat(info.expanded)
+	if err != nil {
+		info.err = err
+		return info
+	}
+	info.mode = fi.Mode()
+	info.owner = fileOwner(info.expanded, fi)
+	info.writable = dirWritable(info.expanded)
+	info.worldWritable = worldWritable(info.expanded, fi)
+	info.otherOwner = ownedByOther(info.expanded, fi)
+	return info
+}
+
+// infoCache caches directory metadata by expanded, normalized directory, since the
+// detail pane is redrawn on every key
+type infoCache map[string]dirInfo
+
+// get returns the metadata of a PATH entry's directory
+func (c infoCache) get(path string) dirInfo {
+	key := normalizePath(expandPath(path))
+	info, ok := c[key]
+	if !ok {
+		info = readDirInfo(path)
+		c[key] = info
+	}
+	return info
+}
+
+// drop forgets the metadata of a PATH entry's directory, so the next get reads it again
+func (c infoCache) drop(path string) {
+	delete(c, normalizePath(expandPath(path)))
+}
+
+// detailPaneLines describes the entry under the cursor: its directory's metadata,
+// duplicate status, and its executables with their shadow status
+func (m model) detailPaneLines() []string {
+	idx := m.cursorIndex()
+	if idx < 0 {
+		return []string{"No entry selected"}
+	}
+	entry := m.paths[idx]
+	info := m.infos.get(entry.path)
+	yesNo := map[bool]string{true: "yes", false: "no"}
+
+	var lines []string
+	field := func(name, value string) {
+		lines = append(lines, fmt.Sprintf("%-12s %s", name+":", value))
+	}
+	if entry.path == "" {
+		field("Empty", m.backend.Var().empty)
+	} else {
+		if info.expanded != entry.path {
+			field("Expanded", info.expanded)
+		}
+		if info.link != "" {
+			field("Symlink to", info.link)
+		}
+		if info.resolved != "" && info.resolved != info.expanded {
+			field("Resolved", info.resolved)
+		}
+		if info.err != nil {
+			field("Error", info.err.Error())
+		} else {
+			if info.owner != "" {
+				field("Owner", info.owner)
+			}
+			field("Permissions", info.mode.String())
+			field("Writable", yesNo[info.writable])
+		}
+	}
+
+	key := normalizePath(info.expanded)
+	for j, p := range m.paths[:idx] {
+		if p.source == entry.source && normalizePath(expandPath(p.path)) == key {
+			field("Duplicate", fmt.Sprintf("yes, of entry %d (%s)", j+1, p.path))
+			break
+		}
+	}
+
+	for _, f := range m.auditPaths() {
+		if f.index == idx {
+			field("Audit", f.severity.String()+": "+f.message)
+		}
+	}
+
+	if !m.backend.Var().commands {
+		return lines
+	}
+	analysis := m.analyzeShadows()
+	shadows, shadowedBy := analysis.shadows(idx), analysis.shadowedBy(idx)
+	field("Executables", fmt.Sprintf("%d (%d hide later entries, %d hidden by earlier entries)",
+		len(analysis.execs[idx]), len(shadows), len(shadowedBy)))
+	status := make(map[string]string)
+	for _, c := range shadows {
+		status[c.command] = "hides " + m.entryPaths(c.entries)
+	}
+	for _, c := range shadowedBy {
+		status[c.command] = "hidden by " + m.entryPaths(c.entries)
+	}
+	for _, e := range analysis.execs[idx] {
+		line := "  " + e.file
+		if s, ok := status[e.name]; ok {
+			line = fmt.Sprintf("  %-24s %s", e.file, s)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// scrollDetail scrolls the detail pane with the given listState method, starting
+// from the top whenever the cursor has moved to another entry
+func (m *model) scrollDetail(scroll func(l *listState, itemCount int)) {
+	if idx := m.cursorIndex(); idx != m.detailFor {
+		m.detailFor = idx
+		m.detail.ScrollHome()
+	}
+	scroll(&m.detail, len(m.detailPaneLines()))
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Kinds of diff lines
+const (
+	diffSame    = iota
+	diffAdded   // new entry
+	diffRemoved // entry that is gone
+	diffEdited  // entry whose text changed
+	diffMoved   // entry at a new position (or in a new section)
+)
+
+// diffLine is one line of the diff of a section: an entry of the new value, or an
+// entry of the old value that is gone
+type diffLine struct {
+	kind int
+	path string
+	old  string // previous text of an edited entry
+	note string // where a moved entry came from or went to
+}
+
+// diffSection is the diff of one section of the edited variable
+type diffSection struct {
+	title string
+	lines []diffLine
+}
+
+// diffPaths returns the diff turning before into after, in the order of after with
+// removed entries where they were. An entry that is removed and added again is shown
+// as moved; a removed entry replaced at the same place by an entry that was edited
+// rather than added in this session (fresh[j] false) is an edit, unless it moved to
+// another section (gone[i] true, if gone is given).
+func diffPaths(before, after []string, fresh, gone []bool) []diffLine {
+	// Longest common subsequence: lcs[i][j] is its length for before[i:] and after[j:]
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Entries outside the common subsequence that appear on both sides were moved
+	var removed, added []string
+	for i, j := 0, 0; i < len(before) || j < len(after); {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			i, j = i+1, j+1
+		case j == len(after) || (i < len(before) && lcs[i+1][j] >= lcs[i][j+1]):
+			removed = append(removed, before[i])
+			i++
+		default:
+			added = append(added, after[j])
+			j++
+		}
+	}
+	moved := make(map[string]int)
+	for _, p := range removed {
+		if n := slices.Index(added, p); n >= 0 {
+			added = slices.Delete(added, n, n+1)
+			moved[p]++
+		}
+	}
+	movedFrom := make(map[string]int) // path -> position in before, for moves
+	for i, p := range before {
+		if _, ok := moved[p]; ok {
+			if _, seen := movedFrom[p]; !seen {
+				movedFrom[p] = i + 1
+			}
+		}
+	}
+
+	var lines []diffLine
+	var dels, adds []diffLine // pending changes between two common entries
+	var away []bool           // for each of dels, whether the entry moved to another section
+	var edited []bool         // for each of adds, whether the entry was edited
+	flush := func() {
+		// Pair removals with edited entries at the same place
+		paired := make([]bool, len(dels))
+		n := 0
+		for k, add := range adds {
+			for n < len(dels) && away[n] {
+				n++
+			}
+			if edited[k] && n < len(dels) {
+				adds[k] = diffLine{kind: diffEdited, path: add.path, old: dels[n].path}
+				paired[n] = true
+				n++
+			}
+		}
+		for k, del := range dels {
+			if !paired[k] {
+				lines = append(lines, del)
+			}
+		}
+		lines = append(lines, adds...)
+		dels, adds, away, edited = nil, nil, nil, nil
+	}
+	movedOut, movedIn := make(map[string]int), make(map[string]int)
+	for i, j := 0, 0; i < len(before) || j < len(after); {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			flush()
+			lines = append(lines, diffLine{kind: diffSame, path: after[j]})
+			i, j = i+1, j+1
+		case j == len(after) || (i < len(before) && lcs[i+1][j] >= lcs[i][j+1]):
+			if movedOut[before[i]] < moved[before[i]] {
+				movedOut[before[i]]++ // shown where it went
+			} else {
+				dels = append(dels, diffLine{kind: diffRemoved, path: before[i]})
+				away = append(away, i < len(gone) && gone[i])
+			}
+			i++
+		default:
+			if p := after[j]; movedIn[p] < moved[p] {
+				movedIn[p]++
+				flush()
+				lines = append(lines, diffLine{kind: diffMoved, path: p, note: fmt.Sprintf("moved from #%d", movedFrom[p])})
+			} else {
+				adds = append(adds, diffLine{kind: diffAdded, path: p})
+				edited = append(edited, !fresh[j])
+			}
+			j++
+		}
+	}
+	flush()
+	return lines
+}
+
+// sectionMoves returns, for each section, the paths with more entries in it than
+// before and fewer in another section (moved in), and the paths with fewer entries
+// than before and more in another section (moved out)
+func sectionMoves(before, after [][]string) (in, out []map[string]bool) {
+	change := make([]map[string]int, len(after)) // entries after minus entries before
+	for s := range after {
+		change[s] = make(map[string]int)
+		for _, p := range after[s] {
+			change[s][p]++
+		}
+		for _, p := range before[s] {
+			change[s][p]--
+		}
+	}
+	in, out = make([]map[string]bool, len(after)), make([]map[string]bool, len(after))
+	for s := range after {
+		in[s], out[s] = make(map[string]bool), make(map[string]bool)
+	}
+	for s := range after {
+		for p, n := range change[s] {
+			for t := range after {
+				if n > 0 && t != s && change[t][p] < 0 {
+					in[s][p], out[t][p] = true, true
+				}
+			}
+		}
+	}
+	return in, out
+}
+
+// markSectionMoves turns an entry removed from one section and added to another into
+// a move, noting the other section on both lines
+func markSectionMoves(sections []diffSection, names []string) {
+	for a := range sections {
+		for i, from := range sections[a].lines {
+			if from.kind != diffRemoved {
+				continue
+			}
+			for b := range sections {
+				if b == a {
+					continue
+				}
+				k := slices.IndexFunc(sections[b].lines, func(l diffLine) bool {
+					return l.kind == diffAdded && l.path == from.path
+				})
+				if k >= 0 {
+					sections[a].lines[i].note = "moved to " + names[b]
+					sections[b].lines[k] = diffLine{kind: diffMoved, path: from.path, note: "moved from " + names[a]}
+					break
+				}
+			}
+		}
+	}
+}
+
+// diffEntries returns the paths that would be saved for a section, like sectionPaths,
+// and whether each entry was added in this session
+func diffEntries(paths []pathEntry, section string) (after []string, fresh []bool) {
+	for _, p := range paths {
+		if p.source == section && !p.deleted {
+			after = append(after, p.path)
+			fresh = append(fresh, p.added)
+		}
+	}
+	return after, fresh
+}
+
+// pendingDiff compares the edited entries with the current value of each section:
+// the value at startup in env mode, the value in the store otherwise, so it shows
+// exactly what saving would write
+func (m model) pendingDiff() ([]diffSection, error) {
+	v := m.backend.Var()
+	if !persists(m.backend) {
+		after, fresh := diffEntries(m.paths, "")
+		return []diffSection{{
+			title: v.name + " (" + m.backend.Describe() + ")",
+			lines: diffPaths(v.split(m.originalPath), after, fresh, nil),
+		}}, nil
+	}
+	current, err := m.backend.Load()
+	if err != nil {
+		return nil, err
+	}
+	// Sections the store doesn't persist (inherited entries) are shown after the others
+	names := slices.Clone(m.backend.Sections())
+	for _, p := range slices.Concat(current, m.paths) {
+		if !slices.Contains(names, p.source) {
+			names = append(names, p.source)
+		}
+	}
+	befores, afters, freshes := make([][]string, len(names)), make([][]string, len(names)), make([][]bool, len(names))
+	for n, name := range names {
+		befores[n] = sectionPaths(current, name)
+		afters[n], freshes[n] = diffEntries(m.paths, name)
+	}
+	// Entries that changed section are moves: neither side of one is taken for an edit
+	// within its section
+	movedIn, movedOut := sectionMoves(befores, afters)
+	var sections []diffSection
+	for n, name := range names {
+		title := v.name + " in " + m.backend.Describe() + ", " + name + " section"
+		if !hasSection(m.backend, name) {
+			title += " (not saved)"
+		}
+		for j, p := range afters[n] {
+			freshes[n][j] = freshes[n][j] || movedIn[n][p]
+		}
+		gone := make([]bool, len(befores[n]))
+		for i, p := range befores[n] {
+			gone[i] = movedOut[n][p]
+		}
+		sections = append(sections, diffSection{
+			title: title,
+			lines: diffPaths(befores[n], afters[n], freshes[n], gone),
+		})
+	}
+	markSectionMoves(sections, names)
+	return sections, nil
+}
+
+// diffRow is a line of the diff view with its style
+type diffRow struct {
+	text, style string
+}
+
+// diffView is a full-screen, scrollable diff of the pending changes
+type diffView struct {
+	rows         []diffRow
+	list         listState
+	returnToQuit bool // reopen the quit prompt when closed
+}
+
+// newDiffView renders the pending changes of m into a diff view
+func newDiffView(m model, returnToQuit bool) *diffView {
+	d := &diffView{returnToQuit: returnToQuit}
+	sections, err := m.pendingDiff()
+	if err != nil {
+		d.rows = []diffRow{{" Error reading " + m.backend.Describe() + ": " + err.Error(), ansiRed}}
+	}
+	counts := make(map[int]int)
+	for _, s := range sections {
+		if len(d.rows) > 0 {
+			d.rows = append(d.rows, diffRow{})
+		}
+		d.rows = append(d.rows, diffRow{" " + s.title, ansiBold})
+		if len(s.lines) == 0 {
+			d.rows = append(d.rows, diffRow{"   (empty)", ansiGrey})
+		}
+		for _, l := range s.lines {
+			if l.kind != diffRemoved || l.note == "" { // moves to another section count once
+				counts[l.kind]++
+			}
+			d.rows = append(d.rows, l.row(m.backend.Var()))
+		}
+	}
+	summary := fmt.Sprintf(" %d added, %d removed, %d moved, %d edited", counts[diffAdded], counts[diffRemoved], counts[diffMoved], counts[diffEdited])
+	if counts[diffAdded]+counts[diffRemoved]+counts[diffMoved]+counts[diffEdited] == 0 {
+		summary = " No changes"
+	}
+	d.rows = append([]diffRow{{summary, ""}, {}}, d.rows...)
+	d.list.SetViewHeight(m.height, len(d.rows))
+	return d
+}
+
+// row renders a diff line: a marker, the entry and a note
+func (l diffLine) row(v pathVar) diffRow {
+	path := l.path
+	if path == "" {
+		path = emptyEntryText(v)
+	}
+	switch l.kind {
+	case diffAdded:
+		return diffRow{" + " + path, ansiGreen}
+	case diffRemoved:
+		if l.note != "" {
+			return diffRow{" - " + path + "  (" + l.note + ")", ansiGrey}
+		}
+		return diffRow{" - " + path, ansiRed}
+	case diffEdited:
+		old := l.old
+		if old == "" {
+			old = emptyEntryText(v)
+		}
+		return diffRow{" ~ " + path + "  (was " + old + ")", ansiYellow}
+	case diffMoved:
+		return diffRow{" > " + path + "  (" + l.note + ")", ansiBlue}
+	}
+	return diffRow{"   " + path, ""}
+}
+
+// Update handles input for the diff view.
+// Returns nil to close the diff view.
+func (d *diffView) Update(msg tea.KeyMsg) *diffView {
+	switch msg.String() {
+	case keyUp, keyUpAlt:
+		d.list.ScrollUp()
+	case keyDown, keyDownAlt:
+		d.list.ScrollDown(len(d.rows))
+	case keyPgUp, keyPgUpAlt:
+		d.list.ScrollPageUp()
+	case keyPgDown, keyPgDownAlt:
+		d.list.ScrollPageDown(len(d.rows))
+	case keyHome, keyHomeAlt:
+		d.list.ScrollHome()
+	case keyEnd, keyEndAlt:
+		d.list.ScrollEnd(len(d.rows))
+	case keyEsc, keyDiff, keyQuit:
+		return nil
+	}
+	return d
+}
+
+// View renders the diff view
+func (d *diffView) View(viewWidth int) string {
+	var sb strings.Builder
+	start, end := d.list.VisibleRange(len(d.rows))
+	scrollbar := d.list.RenderScrollbar(len(d.rows))
+
+	for i := start; i < end; i++ {
+		text := truncateLine(d.rows[i].text, viewWidth-2)
+		line := text
+		if d.rows[i].style != "" {
+			line = d.rows[i].style + text + ansiReset
+		}
+		if padding := viewWidth - 2 - utf8.RuneCountInString(text); padding > 0 {
+			line += strings.Repeat(" ", padding)
+		}
+		sb.WriteString(line + " " + scrollbar[i-start] + "\n")
+	}
+
+	// Pad remaining lines if content is shorter than viewport
+	for i := end - start; i < d.list.viewHeight; i++ {
+		scrollChar := " "
+		if i < len(scrollbar) {
+			scrollChar = scrollbar[i]
+		}
+		sb.WriteString(strings.Repeat(" ", viewWidth-1) + scrollChar + "\n")
+	}
+	return sb.String()
+}
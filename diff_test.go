@@ -0,0 +1,112 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// diffText renders diff lines as "marker path (old or note)" for comparison
+func diffText(lines []diffLine) []string {
+	var out []string
+	for _, l := range lines {
+		s := []string{" ", "+", "-", "~", ">"}[l.kind] + " " + l.path
+		if l.old != "" {
+			s += " (was " + l.old + ")"
+		}
+		if l.note != "" {
+			s += " (" + l.note + ")"
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestDiffPaths(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after []string
+		fresh         []bool
+		want          []string
+	}{
+		{
+			name:   "unchanged",
+			before: []string{"/a", "/b"},
+			after:  []string{"/a", "/b"},
+			fresh:  []bool{false, false},
+			want:   []string{"  /a", "  /b"},
+		},
+		{
+			name:   "added and removed",
+			before: []string{"/a", "/b"},
+			after:  []string{"/a", "/c"},
+			fresh:  []bool{false, true},
+			want:   []string{"  /a", "- /b", "+ /c"},
+		},
+		{
+			name:   "edited in place",
+			before: []string{"/a", "/b", "/c"},
+			after:  []string{"/a", "/x", "/c"},
+			fresh:  []bool{false, false, false},
+			want:   []string{"  /a", "~ /x (was /b)", "  /c"},
+		},
+		{
+			name:   "moved",
+			before: []string{"/a", "/b", "/c"},
+			after:  []string{"/c", "/a", "/b"},
+			fresh:  []bool{false, false, false},
+			want:   []string{"> /c (moved from #3)", "  /a", "  /b"},
+		},
+		{
+			name:   "duplicate added",
+			before: []string{"/a", "/b"},
+			after:  []string{"/a", "/b", "/a"},
+			fresh:  []bool{false, false, true},
+			want:   []string{"  /a", "  /b", "+ /a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffText(diffPaths(tt.before, tt.after, tt.fresh, nil)); !slices.Equal(got, tt.want) {
+				t.Errorf("diffPaths() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffPathsGoneIsNotAnEdit(t *testing.T) {
+	// /b moved to another section: the edited /x next to it is not its new text
+	before := []string{"/a", "/b", "/c"}
+	after := []string{"/a", "/x", "/c"}
+	got := diffText(diffPaths(before, after, []bool{false, false, false}, []bool{false, true, false}))
+	if want := []string{"  /a", "- /b", "+ /x", "  /c"}; !slices.Equal(got, want) {
+		t.Errorf("diffPaths() = %q, want %q", got, want)
+	}
+}
+
+func TestPendingDiffSectionMove(t *testing.T) {
+	// /u1 moves to the top of the system section, where /s1 is deleted
+	m := newTestModel(t, []string{"/s1", "/s2"}, []string{"/u1", "/u2"})
+	m.paths[0].deleted = true
+	m.paths[2].selected = true
+	m.changeSectionOfSelection(true)
+
+	sections, err := m.pendingDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 2 {
+		t.Fatalf("got %d sections, want 2", len(sections))
+	}
+	if got, want := diffText(sections[0].lines), []string{"- /s1", "> /u1 (moved from user)", "  /s2"}; !slices.Equal(got, want) {
+		t.Errorf("system = %q, want %q", got, want)
+	}
+	if got, want := diffText(sections[1].lines), []string{"- /u1 (moved to system)", "  /u2"}; !slices.Equal(got, want) {
+		t.Errorf("user = %q, want %q", got, want)
+	}
+
+	// The summary counts the move once
+	d := newDiffView(m, false)
+	if got, want := d.rows[0].text, " 0 added, 1 removed, 1 moved, 0 edited"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
@@ -8,26 +8,54 @@ type pathEntry struct {
 	deleted  bool   // true if entry is marked for deletion
 	added    bool   // true if entry was added in this session
 	exists   bool   // true if directory exists on disk
+	selected bool   // true if entry is part of the multi-selection
 }
 
 // insertPathEntry inserts a new entry at the end of its source section
 func insertPathEntry(paths []pathEntry, entry pathEntry) []pathEntry {
-	// Find the insertion point: end of the matching source section
-	insertIdx := len(paths)
-	if entry.source == "system" {
-		// Insert at the end of system entries (before user entries start)
+	_, end := sectionBounds(paths, entry.source)
+	return insertPathEntryAt(paths, end, entry)
+}
+
+// sectionBounds returns the [start, end) range of entries with the given source.
+// If the section is empty, start == end is the position where a new entry would go:
+// system entries come before user entries, everything else goes at the end.
+func sectionBounds(paths []pathEntry, source string) (start, end int) {
+	start = -1
+	for i, p := range paths {
+		if p.source == source {
+			if start == -1 {
+				start = i
+			}
+			end = i + 1
+		}
+	}
+	if start != -1 {
+		return start, end
+	}
+	if source == "system" {
 		for i, p := range paths {
 			if p.source == "user" {
-				insertIdx = i
-				break
+				return i, i
 			}
 		}
 	}
-	// For user entries, insertIdx stays at len(paths) - append at end
+	return len(paths), len(paths)
+}
 
-	// Insert at the found position
+// insertPathEntryAt inserts an entry at the given index
+func insertPathEntryAt(paths []pathEntry, idx int, entry pathEntry) []pathEntry {
 	paths = append(paths, pathEntry{})
-	copy(paths[insertIdx+1:], paths[insertIdx:])
-	paths[insertIdx] = entry
+	copy(paths[idx+1:], paths[idx:])
+	paths[idx] = entry
 	return paths
 }
+
+// movePathEntry moves the entry at index from so that it ends up at index to
+// (an index into the resulting slice) and marks it as modified
+func movePathEntry(paths []pathEntry, from, to int) []pathEntry {
+	entry := paths[from]
+	entry.modified = true
+	paths = append(paths[:from], paths[from+1:]...)
+	return insertPathEntryAt(paths, to, entry)
+}
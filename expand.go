@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// expandPath expands the variable references an entry may contain on any platform:
+// a leading ~ (home directory), $VAR and ${VAR} as in POSIX shells, and %VAR% as in
+// REG_EXPAND_SZ values. References to unset variables are kept as they are, so the
+// result never silently drops part of the path.
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = home + path[1:]
+		}
+	}
+	if !strings.ContainsAny(path, "$%") {
+		return path
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(path); {
+		if name, n := varReference(path[i:]); n > 0 {
+			if value, ok := os.LookupEnv(name); ok {
+				b.WriteString(value)
+				i += n
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+		i++
+	}
+	return b.String()
+}
+
+// varReference parses a $VAR, ${VAR} or %VAR% reference at the start of s and returns
+// the variable name and the length of the reference, or n == 0 if there is none
+func varReference(s string) (name string, n int) {
+	switch {
+	case strings.HasPrefix(s, "${"):
+		if end := strings.IndexByte(s, '}'); end > 2 {
+			return s[2:end], end + 1
+		}
+	case s[0] == '$':
+		end := 1
+		for end < len(s) && isVarNameByte(s[end], end == 1) {
+			end++
+		}
+		if end > 1 {
+			return s[1:end], end
+		}
+	case s[0] == '%':
+		if end := strings.IndexByte(s[1:], '%'); end > 0 {
+			return s[1 : end+1], end + 2
+		}
+	}
+	return "", 0
+}
+
+// isVarNameByte reports whether c can appear in a $VAR name (letters, digits and
+// underscores, not starting with a digit)
+func isVarNameByte(c byte, first bool) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || !first && '0' <= c && c <= '9'
+}
+
+// pathExists checks if an entry's directory exists, after expanding variable references
+func pathExists(path string) bool {
+	return dirExists(expandPath(path))
+}
@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestExpandPath(t *testing.T) {
+	t.Setenv("HOME", "/home/u")
+	t.Setenv("FOO", "/opt")
+	t.Setenv("EMPTY", "")
+	t.Setenv("X1", "/x1")
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/usr/bin", "/usr/bin"},
+		{"~", "/home/u"},
+		{"~/bin", "/home/u/bin"},
+		{`~\bin`, `/home/u\bin`},
+		{"~user/bin", "~user/bin"},
+		{"/a/~/b", "/a/~/b"},
+		{"$FOO/bin", "/opt/bin"},
+		{"${FOO}bin", "/optbin"},
+		{`%FOO%\bin`, `/opt\bin`},
+		{"$FOO$FOO", "/opt/opt"},
+		{"$X1/bin", "/x1/bin"},
+		{"$EMPTY/bin", "/bin"},
+		{"$FOO_BAR/bin", "$FOO_BAR/bin"}, // the name is FOO_BAR, which is unset
+		{"$NOT_SET_ANYWHERE/bin", "$NOT_SET_ANYWHERE/bin"},
+		{"${NOT_SET_ANYWHERE}/bin", "${NOT_SET_ANYWHERE}/bin"},
+		{"%NOT_SET_ANYWHERE%/bin", "%NOT_SET_ANYWHERE%/bin"},
+		{"/bin/$", "/bin/$"},
+		{"/bin/$/x", "/bin/$/x"},
+		{"$1/bin", "$1/bin"},
+		{"${", "${"},
+		{"${}", "${}"},
+		{"${FOO", "${FOO"},
+		{"100%", "100%"},
+		{"%%", "%%"},
+		{"%FOO", "%FOO"},
+		{"$HOME/bin", "/home/u/bin"},
+	}
+	for _, tt := range tests {
+		if got := expandPath(tt.path); got != tt.want {
+			t.Errorf("expandPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestVarReference(t *testing.T) {
+	tests := []struct {
+		s    string
+		name string
+		n    int
+	}{
+		{"$FOO/bin", "FOO", 4},
+		{"${FOO}/bin", "FOO", 6},
+		{"%FOO%/bin", "FOO", 5},
+		{"$_a1-", "_a1", 4},
+		{"$", "", 0},
+		{"$/", "", 0},
+		{"$9", "", 0},
+		{"${}", "", 0},
+		{"${FOO", "", 0},
+		{"%%", "", 0},
+		{"%FOO", "", 0},
+		{"FOO", "", 0},
+	}
+	for _, tt := range tests {
+		if name, n := varReference(tt.s); name != tt.name || n != tt.n {
+			t.Errorf("varReference(%q) = %q, %d, want %q, %d", tt.s, name, n, tt.name, tt.n)
+		}
+	}
+}
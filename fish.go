@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// fishUserPathsVar is the fish universal variable holding user PATH additions.
+// fish prepends its elements to PATH.
+const fishUserPathsVar = "fish_user_paths"
+
+// fishArraySep separates the elements of a list in the fish_variables file
+const fishArraySep = "\x1e"
+
+// fishVariablesFile returns the location of fish's universal variables file
+func fishVariablesFile() (string, error) {
+	if file := os.Getenv("PATHED_FISH_VARIABLES"); file != "" {
+		return file, nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "fish", "fish_variables"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "fish", "fish_variables"), nil
+}
+
+// fishEscape encodes a value the way fish writes fish_variables: ASCII letters,
+// digits and '/' are kept, everything else becomes \xHH, \uHHHH or \UHHHHHHHH
+func fishEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r < utf8.RuneSelf && (r == '/' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'):
+			b.WriteRune(r)
+		case r < utf8.RuneSelf:
+			fmt.Fprintf(&b, `\x%.2x`, r)
+		case r <= 0xFFFF:
+			fmt.Fprintf(&b, `\u%.4x`, r)
+		default:
+			fmt.Fprintf(&b, `\U%.8x`, r)
+		}
+	}
+	return b.String()
+}
+
+// fishUnescape decodes a value from fish_variables. A value ending in a backslash or
+// with a malformed \x, \u or \U escape is an error, as saving it back would change it.
+func fishUnescape(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 >= len(s) {
+			return "", errors.New("value ends in an incomplete escape")
+		}
+		i++
+		digits := 0
+		switch s[i] {
+		case 'x':
+			digits = 2
+		case 'u':
+			digits = 4
+		case 'U':
+			digits = 8
+		case 'n':
+			b.WriteByte('\n')
+			continue
+		case 't':
+			b.WriteByte('\t')
+			continue
+		case 'e':
+			b.WriteByte('\x1b')
+			continue
+		default:
+			// \\ and any other escaped character stand for themselves
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+digits >= len(s) {
+			return "", fmt.Errorf("incomplete escape %q", s[i-1:])
+		}
+		n, err := strconv.ParseUint(s[i+1:i+1+digits], 16, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape %q", s[i-1:i+1+digits])
+		}
+		if digits == 2 {
+			b.WriteByte(byte(n))
+		} else {
+			b.WriteRune(rune(n))
+		}
+		i += digits
+	}
+	return b.String(), nil
+}
+
+// parseFishUserPaths extracts the fish_user_paths elements from fish_variables content
+func parseFishUserPaths(content string) ([]string, error) {
+	for _, line := range strings.Split(content, "\n") {
+		name, value, ok := parseFishSetuvar(line)
+		if !ok || name != fishUserPathsVar {
+			continue
+		}
+		value, err := fishUnescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fishUserPathsVar, err)
+		}
+		var paths []string
+		for _, p := range strings.Split(value, fishArraySep) {
+			if p != "" {
+				paths = append(paths, p)
+			}
+		}
+		return paths, nil
+	}
+	return nil, nil
+}
+
+// parseFishSetuvar parses a "SETUVAR [--flags] name:value" line
+func parseFishSetuvar(line string) (name, value string, ok bool) {
+	rest, ok := strings.CutPrefix(line, "SETUVAR ")
+	if !ok {
+		return "", "", false
+	}
+	for strings.HasPrefix(rest, "--") {
+		_, rest, _ = strings.Cut(rest, " ")
+	}
+	name, value, ok = strings.Cut(rest, ":")
+	return name, value, ok
+}
+
+// replaceFishUserPaths returns fish_variables content with fish_user_paths set to paths.
+// Flags on an existing line (e.g. --export) are kept. An empty list removes the variable.
+func replaceFishUserPaths(content string, paths []string) string {
+	escaped := make([]string, len(paths))
+	for i, p := range paths {
+		escaped[i] = fishEscape(p)
+	}
+	value := strings.Join(escaped, fishEscape(fishArraySep))
+
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines {
+		name, _, ok := parseFishSetuvar(strings.TrimSuffix(line, "\n"))
+		if !ok || name != fishUserPathsVar {
+			continue
+		}
+		if len(paths) == 0 {
+			return strings.Join(append(lines[:i], lines[i+1:]...), "")
+		}
+		prefix := line[:strings.Index(line, fishUserPathsVar+":")]
+		lines[i] = prefix + fishUserPathsVar + ":" + value + "\n"
+		return strings.Join(lines, "")
+	}
+
+	if len(paths) == 0 {
+		return content
+	}
+	if content == "" {
+		content = "# This file contains fish universal variable definitions.\n# VERSION: 3.0\n"
+	} else if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + "SETUVAR " + fishUserPathsVar + ":" + value + "\n"
+}
+
+// loadPathsFromFish reads fish_user_paths as user entries and treats the rest
+// of the process PATH as inherited system entries. A missing fish_variables file
+// has no user entries yet.
+func loadPathsFromFish() ([]pathEntry, error) {
+	file, err := fishVariablesFile()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	user, err := parseFishUserPaths(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	return withInheritedPaths(user, pathVariable), nil
+}
+
+// saveFishPaths writes the user entries to fish_user_paths in fish_variables.
+// Only writes the file if the value has actually changed.
+func saveFishPaths(paths []pathEntry) error {
+	file, err := fishVariablesFile()
+	if err != nil {
+		return err
+	}
+
+	user := sectionPaths(paths, "user")
+	return updateFile(file, func(content string) string {
+		return replaceFishUserPaths(content, user)
+	})
+}
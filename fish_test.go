@@ -0,0 +1,177 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFishLoad(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", "/usr/bin")
+	b := fishBackend{}
+
+	// A missing fish_variables file has no user entries yet
+	t.Setenv("PATHED_FISH_VARIABLES", filepath.Join(dir, "missing"))
+	paths, err := b.Load()
+	if err != nil {
+		t.Fatalf("Load() of a missing file = %v, want nil", err)
+	}
+	if got, want := entryList(paths), []string{"system:/usr/bin"}; !slices.Equal(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+
+	file := filepath.Join(dir, "fish_variables")
+	t.Setenv("PATHED_FISH_VARIABLES", file)
+	if err := os.WriteFile(file, []byte(replaceFishUserPaths("", []string{"/u1"})), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	paths, err = b.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := entryList(paths), []string{"user:/u1", "system:/usr/bin"}; !slices.Equal(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+
+	// A malformed value is an error, not an empty user section
+	if err := os.WriteFile(file, []byte("SETUVAR fish_user_paths:\\x2fu1\\x1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Load(); err == nil {
+		t.Error("Load() of a malformed value = nil, want an error")
+	}
+
+	// So is an unreadable file
+	t.Setenv("PATHED_FISH_VARIABLES", dir)
+	if _, err := b.Load(); err == nil {
+		t.Error("Load() of a directory = nil, want an error")
+	}
+}
+
+// fishRoundTrip are entries with characters fish_variables has to escape
+var fishRoundTrip = []string{
+	"/usr/local/bin",
+	"~/bin",
+	"$HOME/go/bin",
+	`/with "quotes" and 'single'`,
+	`/back\slash\`,
+	"/with space",
+	"/café",
+	"/emoji/\U0001f600",
+	"/tab\there",
+	"/semi;colon:",
+}
+
+func TestFishEscape(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"/usr/bin", "/usr/bin"},
+		{"/a b", `/a\x20b`},
+		{"~/x", `\x7e/x`},
+		{`/a\b`, `/a\x5cb`},
+		{"/é", `/\u00e9`},
+		{"/\U0001f600", `/\U0001f600`},
+		{fishArraySep, `\x1e`},
+	}
+	for _, tt := range tests {
+		if got := fishEscape(tt.s); got != tt.want {
+			t.Errorf("fishEscape(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+	for _, s := range fishRoundTrip {
+		if got, err := fishUnescape(fishEscape(s)); err != nil || got != s {
+			t.Errorf("fishUnescape(fishEscape(%q)) = %q, %v", s, got, err)
+		}
+	}
+}
+
+func TestFishUnescape(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{`/a\x20b`, "/a b"},
+		{`\n\t\e`, "\n\t\x1b"},
+		{`/a\\b`, `/a\b`},
+		{`/a\'b`, "/a'b"},
+		{`é\U0001f600`, "é\U0001f600"},
+	}
+	for _, tt := range tests {
+		if got, err := fishUnescape(tt.s); err != nil || got != tt.want {
+			t.Errorf("fishUnescape(%q) = %q, %v, want %q", tt.s, got, err, tt.want)
+		}
+	}
+	for _, s := range []string{`/a\`, `/a\x2`, `/a\xzz`, `\u00e`, `\U0001f60`} {
+		if got, err := fishUnescape(s); err == nil {
+			t.Errorf("fishUnescape(%q) = %q, want an error", s, got)
+		}
+	}
+}
+
+func TestReplaceFishUserPaths(t *testing.T) {
+	header := "# This file contains fish universal variable definitions.\n# VERSION: 3.0\n"
+	other := "SETUVAR fish_color_normal:normal\nSETUVAR --export EDITOR:vi\n"
+	tests := []struct {
+		name    string
+		content string
+		paths   []string
+		want    string
+	}{
+		{"create the file", "", []string{"/a"}, header + "SETUVAR fish_user_paths:/a\n"},
+		{"append", header + other, []string{"/a", "/b"}, header + other + `SETUVAR fish_user_paths:/a\x1e/b` + "\n"},
+		{"append without a final newline", "SETUVAR x:y", []string{"/a"}, "SETUVAR x:y\nSETUVAR fish_user_paths:/a\n"},
+		{"replace in place", header + "SETUVAR fish_user_paths:/old\n" + other, []string{"/a"}, header + "SETUVAR fish_user_paths:/a\n" + other},
+		{"keep flags", "SETUVAR --export fish_user_paths:/old\n" + other, []string{"/a"}, "SETUVAR --export fish_user_paths:/a\n" + other},
+		{"remove", header + "SETUVAR fish_user_paths:/old\n" + other, nil, header + other},
+		{"remove a missing variable", header + other, nil, header + other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceFishUserPaths(tt.content, tt.paths); got != tt.want {
+				t.Errorf("replaceFishUserPaths() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFishSaveLoadRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "fish_variables")
+	t.Setenv("PATHED_FISH_VARIABLES", file)
+	t.Setenv("PATH", "/usr/bin")
+	before := "# This file contains fish universal variable definitions.\n# VERSION: 3.0\nSETUVAR --export EDITOR:vi\n"
+	after := "SETUVAR fish_greeting:\\x1d\n"
+	content := before + "SETUVAR fish_user_paths:/old\n" + after
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := fishBackend{}
+	paths, err := b.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths[0].deleted = true // /old
+	for _, p := range fishRoundTrip {
+		paths = insertPathEntry(paths, pathEntry{path: p, source: "user", added: true})
+	}
+	if err := b.Save(paths); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err = b.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sectionPaths(paths, "user"); !slices.Equal(got, fishRoundTrip) {
+		t.Errorf("user entries = %q, want %q", got, fishRoundTrip)
+	}
+	data, _ := os.ReadFile(file)
+	if got := string(data); got != replaceFishUserPaths(content, fishRoundTrip) || !strings.HasPrefix(got, before) || !strings.HasSuffix(got, after) {
+		t.Errorf("fish_variables =\n%s", got)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// outputFormats are the --format values for env mode output. The default (no format)
+// is the bare value, for capture with $(...).
+var outputFormats = []string{"posix", "fish", "nu", "pwsh", "cmd", "json", "lines"}
+
+// checkOutputFormat returns an error if format is not one of outputFormats or ""
+func checkOutputFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format %q (expected %s)", format, strings.Join(outputFormats, ", "))
+}
+
+// checkVarName returns an error if name can't be used as a variable name in the
+// statements of every output format: letters, digits and underscores, not starting
+// with a digit
+func checkVarName(name string) error {
+	for i := 0; i < len(name); i++ {
+		if !isVarNameByte(name[i], i == 0) {
+			return fmt.Errorf("invalid variable name %q (expected letters, digits and underscores)", name)
+		}
+	}
+	if name == "" {
+		return errors.New("empty variable name")
+	}
+	return nil
+}
+
+// formatValue renders the entries of v for the given output format: a statement that
+// sets the variable when evaluated by the shell, or the entries as JSON or lines.
+// Entries are quoted so they are taken literally; a value that can't be (a line break
+// for cmd and lines, a double quote for cmd) is an error.
+func formatValue(entries []string, v pathVar, format string) (string, error) {
+	if err := checkVarName(v.name); err != nil {
+		return "", err
+	}
+	value := v.join(entries)
+	switch format {
+	case "posix":
+		return "export " + v.name + "=" + posixQuote(value), nil
+	case "fish":
+		// fish keeps variables ending in PATH as lists and joins them with ':' on export
+		if !strings.HasSuffix(v.name, "PATH") {
+			return "set -gx " + v.name + " " + fishQuote(value), nil
+		}
+		quoted := make([]string, len(entries))
+		for i, p := range entries {
+			quoted[i] = fishQuote(p)
+		}
+		return strings.TrimRight("set -gx "+v.name+" "+strings.Join(quoted, " "), " "), nil
+	case "nu":
+		// Nushell keeps PATH as a list; other variables are plain strings
+		if !v.isPath() {
+			return "$env." + v.name + " = " + nuQuote(value), nil
+		}
+		quoted := make([]string, len(entries))
+		for i, p := range entries {
+			quoted[i] = nuQuote(p)
+		}
+		return "$env." + v.name + " = [" + strings.Join(quoted, ", ") + "]", nil
+	case "pwsh":
+		return "$env:" + v.name + " = " + pwshQuote(value), nil
+	case "cmd":
+		// Inside set "...", only a quote or a line break could end the statement
+		if strings.ContainsAny(value, "\"\r\n") {
+			return "", fmt.Errorf("%s can't be set from cmd: it contains a double quote or a line break", v.name)
+		}
+		return `set "` + v.name + "=" + value + `"`, nil
+	case "json":
+		if entries == nil {
+			entries = []string{}
+		}
+		data, _ := json.Marshal(struct {
+			Name    string   `json:"name"`
+			Value   string   `json:"value"`
+			Entries []string `json:"entries"`
+		}{v.name, value, entries})
+		return string(data), nil
+	case "lines":
+		if strings.ContainsAny(value, "\r\n") {
+			return "", fmt.Errorf("%s can't be printed one entry per line: an entry contains a line break", v.name)
+		}
+		return strings.Join(entries, "\n"), nil
+	}
+	return value, nil
+}
+
+// posixQuote quotes s for POSIX shells: single quotes, closing and reopening them
+// around an escaped quote for each embedded one
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes s for fish: single quotes, with \ and ' escaped
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// pwshQuote quotes s for PowerShell: single quotes, doubling each embedded one.
+// PowerShell also ends single-quoted strings at the typographic single quotes, so
+// those are doubled too.
+func pwshQuote(s string) string {
+	return "'" + strings.NewReplacer("'", "''", "\u2018", "\u2018\u2018", "\u2019", "\u2019\u2019", "\u201a", "\u201a\u201a", "\u201b", "\u201b\u201b").Replace(s) + "'"
+}
+
+// nuQuote quotes s for Nushell: double quotes, with \ and " escaped
+func nuQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,107 @@
+package main
+
+import "testing"
+
+func TestFormatValue(t *testing.T) {
+	path := pathVar{name: "PATH", separator: ":"}
+	other := pathVar{name: "MANPATH", separator: ":", keepEmpty: true}
+	scalar := pathVar{name: "GOFLAGS_LIST", separator: ":"}
+	tricky := []string{"/opt/it's", "/a b", "", `C:\x`, `/"q"`, "/100%", "/$HOME", "/‘x’"}
+	tests := []struct {
+		format  string
+		v       pathVar
+		entries []string
+		want    string
+	}{
+		{"", path, []string{"/a", "/b"}, "/a:/b"},
+
+		{"posix", path, []string{"/a", "/b"}, "export PATH='/a:/b'"},
+		{"posix", path, tricky, `export PATH='/opt/it'\''s:/a b::C:\x:/"q":/100%:/$HOME:/‘x’'`},
+		{"posix", path, nil, "export PATH=''"},
+
+		{"fish", path, []string{"/a", "/b"}, "set -gx PATH '/a' '/b'"},
+		{"fish", path, tricky, `set -gx PATH '/opt/it\'s' '/a b' '' 'C:\\x' '/"q"' '/100%' '/$HOME' '/‘x’'`},
+		{"fish", path, nil, "set -gx PATH"},
+		{"fish", other, []string{"/a", ""}, "set -gx MANPATH '/a' ''"},
+		{"fish", scalar, []string{"/a", "/it's"}, `set -gx GOFLAGS_LIST '/a:/it\'s'`},
+
+		{"nu", path, []string{"/a", "/b"}, `$env.PATH = ["/a", "/b"]`},
+		{"nu", path, tricky, `$env.PATH = ["/opt/it's", "/a b", "", "C:\\x", "/\"q\"", "/100%", "/$HOME", "/‘x’"]`},
+		{"nu", path, nil, "$env.PATH = []"},
+		{"nu", other, []string{"/a", `C:\"x"`}, `$env.MANPATH = "/a:C:\\\"x\""`},
+
+		{"pwsh", path, []string{"/a", "/b"}, "$env:PATH = '/a:/b'"},
+		{"pwsh", path, tricky, `$env:PATH = '/opt/it''s:/a b::C:\x:/"q":/100%:/$HOME:/‘‘x’’'`},
+
+		{"cmd", path, []string{`C:\a`, "C:\\b c"}, `set "PATH=C:\a:C:\b c"`},
+		{"cmd", path, []string{"%USERPROFILE%", "a&b|c<d>e^f"}, `set "PATH=%USERPROFILE%:a&b|c<d>e^f"`},
+
+		{"json", path, []string{"/a", `/"q"`}, `{"name":"PATH","value":"/a:/\"q\"","entries":["/a","/\"q\""]}`},
+		{"json", path, nil, `{"name":"PATH","value":"","entries":[]}`},
+
+		{"lines", path, []string{"/a", "", "/b c"}, "/a\n\n/b c"},
+	}
+	for _, tt := range tests {
+		got, err := formatValue(tt.entries, tt.v, tt.format)
+		if err != nil {
+			t.Errorf("formatValue(%q, %s, %q) = %v", tt.entries, tt.v.name, tt.format, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("formatValue(%q, %s, %q) =\n  %s\nwant\n  %s", tt.entries, tt.v.name, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestFormatValueRejects(t *testing.T) {
+	path := pathVar{name: "PATH", separator: ":"}
+	tests := []struct {
+		format  string
+		v       pathVar
+		entries []string
+	}{
+		{"cmd", path, []string{`C:\a" & calc & "`}},
+		{"cmd", path, []string{"C:\\a\r\ncalc"}},
+		{"lines", path, []string{"/a\n/b"}},
+		{"posix", pathVar{name: "X;rm -rf ~;Y", separator: ":"}, []string{"/a"}},
+		{"", pathVar{name: "A-B", separator: ":"}, []string{"/a"}},
+		{"fish", pathVar{name: "1X", separator: ":"}, []string{"/a"}},
+		{"pwsh", pathVar{name: "", separator: ":"}, []string{"/a"}},
+	}
+	for _, tt := range tests {
+		if got, err := formatValue(tt.entries, tt.v, tt.format); err == nil {
+			t.Errorf("formatValue(%q, %q, %q) = %q, want an error", tt.entries, tt.v.name, tt.format, got)
+		}
+	}
+}
+
+func TestWithFormatVarName(t *testing.T) {
+	for _, name := range []string{"PATH", "MY_PATH2", "_x"} {
+		if _, err := withFormat(envBackend{v: lookupVar(name)}, "posix"); err != nil {
+			t.Errorf("withFormat(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"A B", "X$(id)", "X;Y", "9X", ""} {
+		for _, format := range []string{"", "posix"} {
+			if _, err := withFormat(envBackend{v: lookupVar(name)}, format); err == nil {
+				t.Errorf("withFormat(%q, %q) = nil, want an error", name, format)
+			}
+		}
+	}
+	// Persistent stores don't print the name in a statement
+	if _, err := withFormat(profileBackend{v: lookupVar("A-B")}, ""); err != nil {
+		t.Errorf("withFormat(profile) = %v, want nil", err)
+	}
+}
+
+func TestProfileVarName(t *testing.T) {
+	if !supportsProfile {
+		t.Skip("profile mode is not supported on this platform")
+	}
+	if _, err := selectBackend(false, true, false, lookupVar("X;Y")); err == nil {
+		t.Error("selectBackend(--profile, \"X;Y\") = nil, want an error")
+	}
+	if _, err := withVar(profileBackend{v: pathVariable}, lookupVar("X;Y")); err == nil {
+		t.Error("withVar(profile, \"X;Y\") = nil, want an error")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Shell integration: the function printed by "pathed init" runs pathed with
+// PATHED_EVAL_FILE naming a temporary file and PATHED_EVAL_FORMAT naming its shell.
+// Env mode then writes the statement setting the edited variable to that file
+// instead of stdout, and only if the value changed, so the function can evaluate
+// the file after any subcommand without capturing its output, and does nothing
+// when the edit was cancelled.
+const (
+	evalFileEnv   = "PATHED_EVAL_FILE"
+	evalFormatEnv = "PATHED_EVAL_FORMAT"
+)
+
+// writeEvalFile writes the statement setting v to entries for the shell integration
+// function. Nothing is written if the value is unchanged.
+func writeEvalFile(file string, entries []string, v pathVar, format string) error {
+	if v.join(entries) == os.Getenv(v.name) {
+		return nil
+	}
+	if err := checkOutputFormat(format); err != nil {
+		return fmt.Errorf("%s: %w", evalFormatEnv, err)
+	}
+	statement, err := formatValue(entries, v, format)
+	if err != nil {
+		return err
+	}
+	if format == "cmd" {
+		// The file is run with call: don't echo it, and keep % from being expanded
+		statement = "@" + strings.ReplaceAll(statement, "%", "%%")
+	}
+	return os.WriteFile(file, []byte(statement+"\n"), 0o600)
+}
+
+// initScripts holds the integration snippet for each shell, which also loads the
+// completion script where there is one
+var initScripts = map[string]string{
+	"bash": `# pathed shell integration for bash: add to ~/.bashrc
+#   eval "$(pathed init bash)"
+pathed() {
+    local file ret
+    file="$(mktemp "${TMPDIR:-/tmp}/pathed.XXXXXX")" || return
+    PATHED_EVAL_FILE="$file" PATHED_EVAL_FORMAT=posix command pathed "$@"
+    ret=$?
+    if [ -s "$file" ]; then
+        . "$file"
+    fi
+    rm -f "$file"
+    return $ret
+}
+eval "$(command pathed completion bash)"
+`,
+	"zsh": `# pathed shell integration for zsh: add to ~/.zshrc
+#   eval "$(pathed init zsh)"
+pathed() {
+    local file ret
+    file="$(mktemp "${TMPDIR:-/tmp}/pathed.XXXXXX")" || return
+    PATHED_EVAL_FILE="$file" PATHED_EVAL_FORMAT=posix command pathed "$@"
+    ret=$?
+    if [ -s "$file" ]; then
+        . "$file"
+    fi
+    rm -f "$file"
+    return $ret
+}
+if (( $+functions[compdef] )); then
+    eval "$(command pathed completion zsh)"
+fi
+`,
+	"fish": `# pathed shell integration for fish: add to ~/.config/fish/config.fish
+#   pathed init fish | source
+function pathed --description 'Edit PATH-like variables of the current shell'
+    set -l file (mktemp)
+    PATHED_EVAL_FILE=$file PATHED_EVAL_FORMAT=fish command pathed $argv
+    set -l ret $status
+    if test -s $file
+        source $file
+    end
+    rm -f $file
+    return $ret
+end
+command pathed completion fish | source
+`,
+	"nu": `# pathed shell integration for Nushell: save to a file and source it from config.nu
+#   pathed init nu | save -f ($nu.default-config-dir | path join pathed.nu)
+#   source ($nu.default-config-dir | path join pathed.nu)
+def "nu-complete pathed" [context: string] {
+    ^pathed __complete ...($context | split row " " | skip 1) | lines | split column "\t" value description
+}
+
+def --env --wrapped pathed [...args: string@"nu-complete pathed"] {
+    let file = (mktemp -t pathed.XXXXXX)
+    with-env {PATHED_EVAL_FILE: $file, PATHED_EVAL_FORMAT: json} { ^pathed ...$args }
+    let out = (open --raw $file | str trim)
+    rm -f $file
+    if ($out | is-not-empty) {
+        let value = ($out | from json)
+        load-env {($value.name): (if $value.name == "PATH" { $value.entries } else { $value.value })}
+    }
+}
+`,
+	"pwsh": `# pathed shell integration for PowerShell: add to $PROFILE
+#   Invoke-Expression (& pathed init pwsh | Out-String)
+function pathed {
+    $file = [System.IO.Path]::GetTempFileName()
+    $exe = Get-Command pathed -CommandType Application | Select-Object -First 1
+    $env:PATHED_EVAL_FILE = $file
+    $env:PATHED_EVAL_FORMAT = 'pwsh'
+    try {
+        & $exe @args
+        $out = Get-Content -Raw $file
+        if ($out) { Invoke-Expression $out }
+    } finally {
+        Remove-Item $file -ErrorAction SilentlyContinue
+        Remove-Item Env:PATHED_EVAL_FILE, Env:PATHED_EVAL_FORMAT -ErrorAction SilentlyContinue
+    }
+}
+& (Get-Command pathed -CommandType Application | Select-Object -First 1) completion pwsh | Out-String | Invoke-Expression
+`,
+	"cmd": `@rem pathed shell integration for cmd.exe: run from a script set as the
+@rem AutoRun value of HKCU\Software\Microsoft\Command Processor
+@rem   for /f "delims=" %%i in ('pathed init cmd') do @%%i
+doskey pathed=if exist "%TEMP%\pathed-eval.cmd" del "%TEMP%\pathed-eval.cmd" $T set "PATHED_EVAL_FILE=%TEMP%\pathed-eval.cmd" $T set "PATHED_EVAL_FORMAT=cmd" $T pathed.exe $* $T if exist "%TEMP%\pathed-eval.cmd" call "%TEMP%\pathed-eval.cmd" $T if exist "%TEMP%\pathed-eval.cmd" del "%TEMP%\pathed-eval.cmd" $T set "PATHED_EVAL_FILE=" $T set "PATHED_EVAL_FORMAT="
+`,
+}
+
+// initShells lists the shells "pathed init" supports, with their aliases
+var initShells = map[string]string{
+	"bash": "bash", "zsh": "zsh", "fish": "fish",
+	"nu": "nu", "nushell": "nu",
+	"pwsh": "pwsh", "powershell": "pwsh",
+	"cmd": "cmd",
+}
+
+// runInit implements "pathed init"
+func runInit(a *cliArgs) int {
+	if len(a.positional) != 1 {
+		return cliError(fmt.Errorf("expected exactly one shell (bash, zsh, fish, nu, pwsh or cmd)"))
+	}
+	shell, ok := initShells[a.positional[0]]
+	if !ok {
+		return cliError(fmt.Errorf("unknown shell %q (expected bash, zsh, fish, nu, pwsh or cmd)", a.positional[0]))
+	}
+	fmt.Print(initScripts[shell])
+	return 0
+}
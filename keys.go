@@ -2,33 +2,66 @@ package main
 
 // Key bindings
 const (
-	keyQuit      = "q"
-	keyForceQuit = "ctrl+c"
-	keyUp        = "up"
-	keyUpAlt     = "k"
-	keyDown      = "down"
-	keyDownAlt   = "j"
-	keyLeft      = "left"
-	keyRight     = "right"
-	keyPgDown    = "pgdown"
-	keyPgDownAlt = "ctrl+d"
-	keyPgUp      = "pgup"
-	keyPgUpAlt   = "ctrl+u"
-	keyHome      = "home"
-	keyHomeAlt   = "g"
-	keyEnd       = "end"
-	keyEndAlt    = "G"
-	keyMoveUp    = "shift+up"
-	keyMoveUpAlt = "K"
-	keyMoveDn    = "shift+down"
-	keyMoveDnAlt = "J"
-	keyDelete    = "delete"
-	keyEnter     = "enter"
-	keyEsc       = "esc"
-	keySelect    = "tab"
-	keyAddUser   = "a"
-	keyAddSystem = "A"
-	keyClean     = "c"
-	keyHelp      = "?"
-	keyHelpAlt   = "h"
+	keyQuit        = "q"
+	keyForceQuit   = "ctrl+c"
+	keyUp          = "up"
+	keyUpAlt       = "k"
+	keyDown        = "down"
+	keyDownAlt     = "j"
+	keyLeft        = "left"
+	keyRight       = "right"
+	keyPgDown      = "pgdown"
+	keyPgDownAlt   = "ctrl+d"
+	keyPgUp        = "pgup"
+	keyPgUpAlt     = "ctrl+u"
+	keyHome        = "home"
+	keyHomeAlt     = "g"
+	keyEnd         = "end"
+	keyEndAlt      = "G"
+	keyMoveUp      = "shift+up"
+	keyMoveUpAlt   = "K"
+	keyMoveDn      = "shift+down"
+	keyMoveDnAlt   = "J"
+	keyMoveTop     = "T"
+	keyMoveBottom  = "B"
+	keyDelete      = "delete"
+	keyEnter       = "enter"
+	keyEsc         = "esc"
+	keySelect      = "tab"
+	keyAddUser     = "a"
+	keyAddSystem   = "A"
+	keyClean       = "c"
+	keyEditText    = "e"
+	keyInsertUser  = "i"
+	keyInsertSys   = "I"
+	keyTypePath    = "ctrl+e"
+	keyUndo        = "u"
+	keyRedo        = "ctrl+r"
+	keySearch      = "/"
+	keySearchNext  = "n"
+	keySearchPrev  = "N"
+	keyFilter      = "f"
+	keyExpand      = "x"
+	keyShadows     = "s"
+	keyWhich       = "w"
+	keyDetail      = "o"
+	keyDetailUp    = "["
+	keyDetailDn    = "]"
+	keyDetailPgUp  = "{"
+	keyDetailPgDn  = "}"
+	keyPickVar     = "$"
+	keyDiff        = "D"
+	keyRescan      = "ctrl+l"
+	keyMergeTheirs = "a" // merge view: take all external changes
+	keyMergeOurs   = "o" // merge view: keep all of ours
+	keyMark        = " "
+	keyCut         = "d" // dd
+	keyYank        = "y" // yy
+	keyPasteBelow  = "p"
+	keyPasteAbove  = "P"
+	keyDuplicate   = "+"
+	keyVisual      = "v"
+	keySection     = "m"
+	keyHelp        = "?"
+	keyHelpAlt     = "h"
 )
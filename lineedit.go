@@ -0,0 +1,265 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// lineEditor is a full-screen single-line input for typing a path directly.
+// Unlike the browser, it accepts paths that don't exist or contain variable references.
+type lineEditor struct {
+	value        []rune
+	cursor       int      // cursor position in runes
+	editingIndex int      // which path entry we're editing (-1 for add mode)
+	addSource    string   // "user" or "system" when adding new entry (empty when editing)
+	completions  []string // candidate directories from the last Tab press
+	height       int      // total height available for the view
+}
+
+func newLineEditor(initial string, editingIndex int, addSource string, height int) *lineEditor {
+	value := []rune(initial)
+	return &lineEditor{
+		value:        value,
+		cursor:       len(value),
+		editingIndex: editingIndex,
+		addSource:    addSource,
+		height:       height,
+	}
+}
+
+// Update handles input for the line editor
+// Returns: updated editor (nil if closed), tea.Cmd, entered path (empty if cancelled)
+func (e *lineEditor) Update(msg tea.KeyMsg) (*lineEditor, tea.Cmd, string) {
+	switch msg.String() {
+	case keyEnter:
+		return nil, nil, strings.TrimSpace(string(e.value))
+
+	case keyEsc:
+		return nil, nil, ""
+
+	case "left", "ctrl+b":
+		if e.cursor > 0 {
+			e.cursor--
+		}
+
+	case "right", "ctrl+f":
+		if e.cursor < len(e.value) {
+			e.cursor++
+		}
+
+	case "home", "ctrl+a":
+		e.cursor = 0
+
+	case "end", "ctrl+e":
+		e.cursor = len(e.value)
+
+	case "alt+left", "ctrl+left", "alt+b":
+		e.cursor = e.wordStart()
+
+	case "alt+right", "ctrl+right", "alt+f":
+		e.cursor = e.wordEnd()
+
+	case "backspace", "ctrl+h":
+		if e.cursor > 0 {
+			e.value = append(e.value[:e.cursor-1], e.value[e.cursor:]...)
+			e.cursor--
+		}
+
+	case "delete", "ctrl+d":
+		if e.cursor < len(e.value) {
+			e.value = append(e.value[:e.cursor], e.value[e.cursor+1:]...)
+		}
+
+	case "ctrl+w", "alt+backspace":
+		// Delete the path component (or word) before the cursor
+		start := e.wordStart()
+		e.value = append(e.value[:start], e.value[e.cursor:]...)
+		e.cursor = start
+
+	case "ctrl+u":
+		e.value = e.value[e.cursor:]
+		e.cursor = 0
+
+	case "ctrl+k":
+		e.value = e.value[:e.cursor]
+
+	case keySelect:
+		e.complete()
+		return e, nil, ""
+
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			runes := make([]rune, 0, len(e.value)+len(msg.Runes))
+			runes = append(runes, e.value[:e.cursor]...)
+			runes = append(runes, msg.Runes...)
+			runes = append(runes, e.value[e.cursor:]...)
+			e.value = runes
+			e.cursor += len(msg.Runes)
+		}
+	}
+	e.completions = nil
+	return e, nil, ""
+}
+
+// isWordBoundary returns true for runes that separate words when moving by word
+func isWordBoundary(r rune) bool {
+	return r < utf8.RuneSelf && os.IsPathSeparator(uint8(r)) || unicode.IsSpace(r) || r == os.PathListSeparator
+}
+
+// wordStart returns the position of the start of the word before the cursor
+func (e *lineEditor) wordStart() int {
+	i := e.cursor
+	for i > 0 && isWordBoundary(e.value[i-1]) {
+		i--
+	}
+	for i > 0 && !isWordBoundary(e.value[i-1]) {
+		i--
+	}
+	return i
+}
+
+// wordEnd returns the position of the end of the word after the cursor
+func (e *lineEditor) wordEnd() int {
+	i := e.cursor
+	for i < len(e.value) && isWordBoundary(e.value[i]) {
+		i++
+	}
+	for i < len(e.value) && !isWordBoundary(e.value[i]) {
+		i++
+	}
+	return i
+}
+
+// complete performs tab completion of the last path component against the filesystem.
+// A single match is completed fully; several matches are completed to their common prefix
+// and listed below the input.
+func (e *lineEditor) complete() {
+	value := string(e.value)
+	dir, prefix := splitLastComponent(value)
+	matches := completeDir(dir, prefix)
+	e.completions = nil
+
+	switch len(matches) {
+	case 0:
+		return
+	case 1:
+		value = dir + matches[0] + string(os.PathSeparator)
+	default:
+		common := matches[0]
+		for _, m := range matches[1:] {
+			for !strings.HasPrefix(m, common) {
+				_, size := utf8.DecodeLastRuneInString(common)
+				common = common[:len(common)-size]
+			}
+		}
+		if len(common) > len(prefix) {
+			value = dir + common
+		}
+		e.completions = matches
+	}
+	e.value = []rune(value)
+	e.cursor = len(e.value)
+}
+
+// splitLastComponent splits a path into its directory part (including the trailing
+// separator) and the last, partially typed component
+func splitLastComponent(value string) (dir, prefix string) {
+	i := len(value) - 1
+	for i >= 0 && !os.IsPathSeparator(value[i]) {
+		i--
+	}
+	return value[:i+1], value[i+1:]
+}
+
+// completeDir returns the names of subdirectories of dir that start with prefix.
+// Comparison follows normalizePath, so it is case-insensitive on Windows.
+// Hidden directories are only offered if the prefix starts with a dot.
+func completeDir(dir, prefix string) []string {
+	readDir := expandPath(dir)
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+
+	normalizedPrefix := normalizePath(prefix)
+	var matches []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+		if !strings.HasPrefix(normalizePath(name), normalizedPrefix) {
+			continue
+		}
+		// Follow symlinks so linked directories can be completed too
+		if entry.IsDir() || dirExists(readDir+string(os.PathSeparator)+name) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return strings.ToLower(matches[i]) < strings.ToLower(matches[j])
+	})
+	return matches
+}
+
+// View renders the line editor
+func (e *lineEditor) View(viewWidth int) string {
+	var sb strings.Builder
+
+	// Header
+	header := "Edit path:"
+	if e.editingIndex == -1 {
+		header = "Add path:"
+		if e.addSource == "system" {
+			header = "Add system path:"
+		}
+	}
+	sb.WriteString(ansiBold + header + ansiReset + "\n")
+
+	// Input line, scrolled horizontally to keep the cursor visible
+	width := max(1, viewWidth-3) // prompt + cursor cell
+	start := max(0, e.cursor-width+1)
+	end := min(len(e.value), start+width)
+	line := "> " + string(e.value[start:e.cursor])
+	if e.cursor < len(e.value) {
+		line += ansiReverse + string(e.value[e.cursor]) + ansiNoReverse
+		if e.cursor+1 < end {
+			line += string(e.value[e.cursor+1 : end])
+		}
+	} else {
+		line += ansiReverse + " " + ansiNoReverse
+	}
+	sb.WriteString(line + "\n")
+	rendered := 2
+
+	// Completion candidates
+	if len(e.completions) > 0 {
+		sb.WriteString("\n")
+		rendered++
+		for _, c := range e.completions {
+			if rendered >= e.height {
+				break
+			}
+			if utf8.RuneCountInString(c) > viewWidth-3 {
+				c = string([]rune(c)[:max(0, viewWidth-6)]) + "..."
+			}
+			sb.WriteString("  " + c + "\n")
+			rendered++
+		}
+	}
+
+	// Pad remaining lines
+	for i := rendered; i < e.height; i++ {
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
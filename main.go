@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -14,11 +15,45 @@ const helpText = `pathed - Interactive PATH environment editor
 
 USAGE:
     pathed [OPTIONS]
+    pathed <COMMAND> [OPTIONS]
 
 OPTIONS:
     -h, --help        Show this help message
     -v, --version     Show version
     -r, --registry    Read from and write to Windows registry (Windows only)
+    -p, --profile     Read from and write to shell profile (Linux/macOS only)
+        --fish        Read from and write to fish's fish_user_paths variable
+        --var NAME    Edit another PATH-like variable, e.g. MANPATH or PYTHONPATH
+    -f, --format F    Print a statement for a shell instead of the bare value (default
+                      mode): posix, fish, nu, pwsh, cmd, or json or lines
+
+COMMANDS:
+    list              Print PATH entries (--format text|json|tsv)
+    add <path>        Add an entry (--prepend, --append, --before/--after <entry>,
+                      --source user|system, --if-missing)
+    remove <entry>    Remove entries by path or index (from 'pathed list')
+    move <entry>      Move an entry within its section (--up, --down, --prepend,
+                      --append, --before/--after <entry>)
+    clean             Remove missing/duplicate entries (--missing, --duplicates,
+                      --dry-run, --check exits 1 if anything would be removed)
+    shadows           Report commands provided by more than one entry (--format text|json)
+    audit             Report insecure entries with severities, exit 1 if any are found
+                      (--format text|json, --fail-on low|medium|high)
+    which <command>   Print the executable a command resolves to (-a/--all for every match)
+
+    init <shell>      Print the shell integration function (bash, zsh, fish, nu, pwsh, cmd)
+    completion <shell>
+                      Print the completion script (bash, zsh, fish, pwsh)
+
+    history           List backups written before each persisted change
+    restore <id>      Roll a store back to its state before a backup (--dry-run)
+
+    In default mode, add/remove/move/clean print the resulting PATH for shell capture.
+    With -r/--registry, -p/--profile or --fish they persist the change instead.
+    list, add, remove, move, clean and audit accept --var NAME; add, remove, move
+    and clean accept --format like the TUI.
+
+    Run 'pathed <COMMAND> --help' for command options.
 
 DESCRIPTION:
     pathed provides a TUI for editing your PATH environment variable.
@@ -31,100 +66,229 @@ DESCRIPTION:
       Reads from and writes to the Windows registry. Shows system and user
       PATH entries separately. Changes are persisted directly to the registry.
       No output is produced (shell capture not needed).
+      %VAR% references are kept and the value type (REG_EXPAND_SZ) is
+      preserved; the list shows their expanded path after "=>".
+
+    Profile mode (--profile, Linux/macOS only):
+      Keeps user PATH entries in a managed block in your shell startup file
+      ($PATHED_PROFILE, or ~/.zshrc, ~/.bashrc or ~/.profile based on $SHELL).
+      User entries come first; the rest of PATH is shown as inherited system
+      entries, which are read-only. Open a new shell to pick up changes.
+
+    Fish mode (--fish):
+      Edits the fish_user_paths universal variable in fish's fish_variables
+      file. Its entries are shown as the user section, before the inherited
+      system entries, which are read-only. New fish sessions pick up
+      persisted changes.
+
+    Other variables (--var NAME):
+      Edits MANPATH, LD_LIBRARY_PATH, PYTHONPATH, XDG_DATA_DIRS or any other
+      PATH-like variable with its own separator. Empty MANPATH, INFOPATH and
+      CDPATH segments are meaningful and kept. Fish mode edits PATH only;
+      shadow analysis and which apply to PATH only.
 
 USAGE EXAMPLES:
   Linux/macOS (bash/zsh):
-    export PATH="$(pathed)"
+    eval "$(pathed --format posix)"
+
+  Fish:
+    pathed --format fish | source
 
   Nushell:
-    $env.PATH = (pathed | str trim | split row ';')
+    $env.PATH = (pathed --format lines | lines)
 
   Windows PowerShell:
-    $env:PATH = (pathed)
+    pathed --format pwsh | Invoke-Expression
 
   Windows cmd.exe:
-    for /f "delims=" %i in ('pathed') do set "PATH=%i"
-  
-  Or add a shell function to your profile:
-    # PowerShell ($PROFILE)
-    function Edit-Path { $env:PATH = (pathed) }
+    for /f "delims=" %i in ('pathed --format cmd') do %i
 
+  Or load the shell integration in your profile, so 'pathed' edits the
+  current shell directly (only when something changed):
     # Bash/Zsh (.bashrc or .zshrc)
-    pathed() { export PATH="$(command pathed "$@")"; }
+    eval "$(pathed init bash)"     # or: pathed init zsh
+
+    # Fish (config.fish)
+    pathed init fish | source
+
+    # PowerShell ($PROFILE)
+    Invoke-Expression (& pathed init pwsh | Out-String)
+
+    Nushell and cmd.exe: see 'pathed init nu' and 'pathed init cmd'.
 
 KEY BINDINGS:
     j/k, Up/Down     Navigate
     J/K, Shift+Up/Dn Move entry up/down (within section in registry mode)
+    T/B              Move entry to the top/bottom of its section
+    m                Move entry to the other section, user or system, at its
+                     top or bottom or next to a picked entry (registry mode only)
     Left/Right       Horizontal scroll
     g/G, Home/End    Jump to first/last
     Tab              Edit path (opens directory browser)
+    e                Edit path by typing (pre-filled with current entry)
     a                Add PATH entry (user entry in registry mode)
     A                Add system PATH entry (registry mode only)
+    i                Add PATH entry by typing (user entry in registry mode)
+    I                Add system PATH entry by typing (registry mode only)
     c                Clean (mark duplicates & missing for deletion)
     Del              Toggle delete mark
+    dd               Cut entry (marks it for deletion and yanks it)
+    yy               Yank entry, also to the system clipboard (OSC 52)
+    p/P              Paste yanked entries below/above the cursor (pathed's own
+                     register, not the system clipboard)
+    +                Duplicate entry below itself
+    Space            Select/deselect entry and move down
+    v                Start/end selecting a range with the cursor
+    /                Search (highlights matches; Enter confirms, Esc cancels)
+    n/N              Jump to next/previous match
+    f                Toggle filter (show only matching entries)
+    Esc              Clear selection, then search and filter
+    s                Toggle pane with the commands the entry hides or is hidden by
+    o                Toggle detail pane: expanded path, symlink target, owner,
+                     permissions, writability, duplicate status and executables
+    [/], {/}         Scroll the detail pane by a line/page
+    w                Look up a command under the edited PATH (Tab completes, empty closes)
+    x                Toggle expanded paths (shown for entries with variable references)
+    D                Show a diff of the pending changes (also offered when quitting)
+    Ctrl+L           Read the directories again (existence, details, commands)
+    $                Switch to another PATH-like variable (not with a bare value or --format lines)
+    u                Undo last edit
+    Ctrl+R           Redo last undone edit
     q                Quit (prompts if changes exist)
     Ctrl+C           Force quit
 
+    With entries selected, Del, J/K, T/B, m, dd, yy and + act on all of them:
+    blocks move as a unit and stay within their section.
+
+    To paste from the system clipboard, use the terminal's own paste (such as
+    Ctrl+Shift+V or Cmd+V): it adds the paths the text holds below the cursor,
+    one per line or separated like the variable's value. Pasted entries are
+    new entries in the cursor entry's section.
+
+    In the directory browser, Ctrl+E switches to typing the path.
+    When typing, Tab completes directory names; paths that don't exist yet
+    and variable references like %VAR% or $HOME are accepted as typed.
+
+    A red or yellow ! marks entries flagged by the security audit (empty or
+    relative entries, world-writable directories, directories owned by another
+    user, temporary directories); the detail pane (o) shows the findings.
+
+    Entries may contain ~, $VAR, ${VAR} and %VAR% references on every platform.
+    They are saved as typed, but the missing marker, clean and duplicate
+    detection use the expanded path. References to unset variables are kept.
+
+BACKUPS:
+    Every persisted change (registry, profile or fish mode) first writes a
+    timestamped snapshot with the previous and new entries, the user and the
+    pathed version to $PATHED_STATE_DIR, %LOCALAPPDATA%\pathed (Windows) or
+    $XDG_STATE_HOME/pathed (default ~/.local/state/pathed).
+
 QUIT OPTIONS:
     Default mode:     "Edited" (output modified) / "Original" (output unchanged)
     Registry mode:    "Persist" (save to registry) / "Don't persist" (discard)
+    Profile mode:     "Persist" (save to profile) / "Don't persist" (discard)
+    Fish mode:        "Persist" (save fish_user_paths) / "Don't persist" (discard)
+    Every mode also offers "View diff" to review the pending changes first.
+    If the store was changed by another program while pathed was open,
+    persisting first shows its changes to merge (Space toggles one, a/o all or
+    none, Tab shows the original, external, ours and merged values).
 `
 
 func main() {
-	// Parse command-line flags
-	registryMode := false
-	for _, arg := range os.Args[1:] {
-		switch arg {
-		case "-h", "--help":
-			fmt.Print(helpText)
-			return
-		case "-v", "--version":
-			fmt.Println(version)
-			return
-		case "-r", "--registry":
-			if !supportsRegistry {
-				fmt.Fprintln(os.Stderr, "Error: --registry flag is only supported on Windows")
-				os.Exit(1)
-			}
-			registryMode = true
-		default:
-			fmt.Fprintf(os.Stderr, "Unknown option: %s\nUse --help for usage information.\n", arg)
-			os.Exit(1)
+	// Dispatch non-interactive subcommands
+	if len(os.Args) > 1 {
+		if os.Args[1] == completeCommandName {
+			os.Exit(runComplete(os.Args[2:]))
+		}
+		if cmd := findCommand(os.Args[1]); cmd != nil {
+			os.Exit(runCommand(cmd, os.Args[2:]))
 		}
 	}
 
-	// Open terminal device directly for TUI output, keeping stdout clean for piping
-	tty, err := openTTY()
+	// Parse command-line flags against the same table as completion
+	a, err := rootCommand.parseArgs(os.Args[1:])
+	if err == nil && len(a.positional) > 0 {
+		err = fmt.Errorf("unknown command: %s", a.positional[0])
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening terminal: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\nUse --help for usage information.\n", err)
+		os.Exit(1)
+	}
+	if a.has("help") {
+		fmt.Print(helpText)
+		return
+	}
+	if a.has("version") {
+		fmt.Println(version)
+		return
+	}
+
+	v := pathVariable
+	if a.has("var") {
+		v = lookupVar(a.get("var"))
+	}
+	b, err := selectBackend(a.has("registry"), a.has("profile"), a.has("fish"), v)
+	if err == nil {
+		b, err = withFormat(b, a.get("format"))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	m, err := initialModel(b)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s from %s: %v\n", v.name, b.Describe(), err)
 		os.Exit(1)
 	}
-	defer tty.Close()
 
-	p := tea.NewProgram(initialModel(registryMode), tea.WithAltScreen(), tea.WithOutput(tty))
-	finalModel, err := p.Run()
+	// Open terminal device directly for TUI output, keeping stdout clean for piping
+	f, err := openTTY()
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error opening terminal: %v\n", err)
 		os.Exit(1)
 	}
+	tty := &terminal{File: f}
+	defer tty.Close()
 
-	// Handle output based on mode
-	if m, ok := finalModel.(model); ok {
-		if m.registryMode {
-			// Registry mode: persist to registry if user chose to save
-			if m.saveChanges {
-				if err := savePaths(m.paths); err != nil {
-					fmt.Fprintf(os.Stderr, "Error saving to registry: %v\n", err)
-					os.Exit(1)
-				}
+	m.clipboard = tty
+	for {
+		p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithOutput(tty))
+		finalModel, err := p.Run()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Handle output: persistent stores save if the user chose to, env mode always outputs the value
+		final, ok := finalModel.(model)
+		if !ok {
+			return
+		}
+		if !final.saveChanges {
+			if env, ok := final.backend.(envBackend); ok {
+				env.print(env.v.split(final.originalPath))
+			}
+			return
+		}
+		err = persistPaths(final.backend, final.paths, final.fingerprint)
+		if errors.Is(err, errStoreChanged) {
+			// Changed again after the check on quit: reopen the editor to merge that too
+			var external []pathEntry
+			if external, err = final.externalChange(); err == nil && external != nil {
+				m = final
+				m.saveChanges, m.prompt = false, nil
+				m.mergeView = newMergeView(m, external)
+				continue
 			}
-		} else {
-			// Env mode: always output a PATH string
-			if m.saveChanges {
-				fmt.Println(buildPathString(m.paths))
-			} else {
-				fmt.Println(m.originalPath)
+			if err == nil {
+				err = persistPaths(final.backend, final.paths, final.fingerprint)
 			}
 		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving to %s: %v\n", final.backend.Describe(), err)
+			os.Exit(1)
+		}
+		printPersistNotice(final.backend)
+		return
 	}
 }
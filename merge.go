@@ -0,0 +1,390 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// External changes: the persisted value is fingerprinted when it is loaded and read
+// again before saving. If another program (an installer, another pathed) changed it
+// in the meantime, the merge view lists its changes against the loaded value, so they
+// can be taken into the edited entries instead of being overwritten.
+
+// errStoreChanged is returned when saving edits to a store that another program
+// changed since they were loaded
+var errStoreChanged = errors.New("changed by another program since it was loaded")
+
+// fingerprint identifies the persisted value of each section of b in entries
+func fingerprint(b backend, entries []pathEntry) string {
+	h := sha256.New()
+	for _, section := range b.Sections() {
+		fmt.Fprintf(h, "%s\x00%q\x00", section, sectionPaths(entries, section))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// externalChange reads the store again and returns its entries if they no longer
+// match the fingerprint taken when the editor loaded them, or nil
+func (m model) externalChange() ([]pathEntry, error) {
+	if !persists(m.backend) {
+		return nil, nil
+	}
+	current, err := m.backend.Load()
+	if err != nil || fingerprint(m.backend, current) == m.fingerprint {
+		return nil, err
+	}
+	return current, nil
+}
+
+// mergeItem is an entry another program added to, removed from or moved within a section
+type mergeItem struct {
+	section  string
+	path     string
+	removed  bool   // removed externally
+	moved    bool   // moved externally; otherwise added (if not removed)
+	nth      int    // for a removal, which occurrence of path it is (0 for the first)
+	after    string // for an addition or a move, the entry it follows in the external value ("" for the top)
+	conflict bool   // the entry was also moved or edited in the editor
+	take     bool   // apply the external change to the edited entries
+}
+
+// mergeItems returns the changes between the loaded (original) and the external
+// entries that the edited entries (ours) don't already have. Entries are compared by
+// count, so an external duplicate is a change, and reordered entries are moves. A
+// removal or a move conflicts with ours if the entry was moved or edited there, and
+// defaults to keeping it as it is in ours.
+func mergeItems(b backend, original, external, ours []pathEntry) []mergeItem {
+	var items []mergeItem
+	for _, section := range b.Sections() {
+		o, e := sectionPaths(original, section), sectionPaths(external, section)
+		a := sectionPaths(ours, section)
+		// extra[p] > 0: ours added p as well; < 0: ours removed it as well
+		extra := make(map[string]int)
+		for _, p := range a {
+			extra[p]++
+		}
+		for _, p := range o {
+			extra[p]--
+		}
+		fresh := make([]bool, len(e))
+		for i := range fresh {
+			fresh[i] = true // no edits: an externally edited entry is removed and added
+		}
+		seen := make(map[string]int) // occurrences of each path in the original so far
+		prev := ""                   // the entry before the current one in the external value
+		for _, l := range diffPaths(o, e, fresh, nil) {
+			switch l.kind {
+			case diffSame:
+				seen[l.path]++
+			case diffAdded:
+				if extra[l.path] > 0 {
+					extra[l.path]-- // ours has it too
+				} else {
+					items = append(items, mergeItem{section: section, path: l.path, after: prev, take: true})
+				}
+			case diffRemoved:
+				nth := seen[l.path]
+				seen[l.path]++
+				if extra[l.path] < 0 {
+					extra[l.path]++ // removed on both sides
+					continue
+				}
+				conflict := modifiedEntry(ours, section, l.path, nth)
+				items = append(items, mergeItem{section: section, path: l.path, removed: true, nth: nth, conflict: conflict, take: !conflict})
+			case diffMoved:
+				if extra[l.path] < 0 {
+					extra[l.path]++ // removed in ours
+				} else if !follows(a, l.path, prev) {
+					conflict := modifiedEntry(ours, section, l.path, 0)
+					items = append(items, mergeItem{section: section, path: l.path, moved: true, after: prev, conflict: conflict, take: !conflict})
+				}
+			}
+			if l.kind != diffRemoved {
+				prev = l.path
+			}
+		}
+	}
+	return items
+}
+
+// findEntry returns the index of the nth occurrence of path among the entries of a
+// section that are not deleted, or of its last occurrence if there are fewer, or -1
+func findEntry(paths []pathEntry, section, path string, nth int) int {
+	found := -1
+	for i, p := range paths {
+		if p.source == section && p.path == path && !p.deleted {
+			found = i
+			if nth--; nth < 0 {
+				break
+			}
+		}
+	}
+	return found
+}
+
+// modifiedEntry reports whether the nth occurrence of path in a section of paths was
+// moved or edited
+func modifiedEntry(paths []pathEntry, section, path string, nth int) bool {
+	i := findEntry(paths, section, path, nth)
+	return i >= 0 && paths[i].modified
+}
+
+// follows reports whether the first occurrence of path in entries comes right after
+// prev, or first if prev is ""
+func follows(entries []string, path, prev string) bool {
+	i := slices.Index(entries, path)
+	if i == 0 {
+		return prev == ""
+	}
+	return i > 0 && entries[i-1] == prev
+}
+
+// mergedPaths applies the merge items to the edited entries, relative to the external
+// value: taken additions become unmodified entries, taken removals disappear and taken
+// moves put the entry after the same entry as externally, while an addition that is
+// not taken is marked for deletion, a removal that is not taken becomes an added entry
+// and a move that is not taken keeps the entry where it is. Entries deleted in the
+// editor that are gone externally are dropped.
+func mergedPaths(ours, external []pathEntry, items []mergeItem, v pathVar) []pathEntry {
+	paths := slices.Clone(ours)
+	for _, item := range items {
+		entry := pathEntry{
+			path:    item.path,
+			source:  item.section,
+			deleted: !item.take,
+			exists:  v.exists(item.path),
+		}
+		if item.removed || item.moved {
+			i := findEntry(paths, item.section, item.path, item.nth)
+			switch {
+			case i < 0:
+				continue
+			case item.removed && item.take:
+				paths = slices.Delete(paths, i, i+1)
+				continue
+			case item.removed:
+				paths[i].added = true
+				continue
+			case !item.take:
+				continue
+			}
+			entry = paths[i]
+			paths = slices.Delete(paths, i, i+1)
+		}
+		at, _ := sectionBounds(paths, item.section)
+		for i, p := range paths {
+			if item.after != "" && p.source == item.section && p.path == item.after {
+				at = i + 1
+			}
+		}
+		paths = insertPathEntryAt(paths, at, entry)
+	}
+	return slices.DeleteFunc(paths, func(p pathEntry) bool {
+		return p.deleted && !p.added && !slices.Contains(sectionPaths(external, p.source), p.path)
+	})
+}
+
+// Tabs of the merge view
+const (
+	mergeTabChanges = iota
+	mergeTabOriginal
+	mergeTabExternal
+	mergeTabOurs
+	mergeTabMerged
+	mergeTabCount
+)
+
+// mergeTabNames are the titles of the merge view tabs
+var mergeTabNames = []string{"changes", "original", "external", "ours", "merged"}
+
+// mergeView lets the user pick which external changes to merge into the edited
+// entries. Besides the list of changes, tabs show each section as loaded (original),
+// as it is in the store now (external), as edited (ours) and with the chosen changes
+// applied (merged).
+type mergeView struct {
+	items                    []mergeItem
+	original, external, ours []pathEntry
+	backend                  backend
+	tab                      int
+	list                     listState
+	height                   int
+}
+
+// newMergeView creates a merge view of the external entries into the edited ones
+func newMergeView(m model, external []pathEntry) *mergeView {
+	mv := &mergeView{
+		items:    mergeItems(m.backend, m.base, external, m.paths),
+		original: m.base,
+		external: external,
+		ours:     m.paths,
+		backend:  m.backend,
+		height:   m.height,
+	}
+	mv.resize()
+	return mv
+}
+
+// merged returns the edited entries with the chosen external changes applied
+func (mv *mergeView) merged() []pathEntry {
+	return mergedPaths(mv.ours, mv.external, mv.items, mv.backend.Var())
+}
+
+// mergeHeaderLines is the number of lines above the rows: the title, the tabs and a blank line
+const mergeHeaderLines = 3
+
+// resize fits the rows of the current tab below the header
+func (mv *mergeView) resize() {
+	mv.list.SetViewHeight(max(1, mv.height-mergeHeaderLines), len(mv.rows()))
+}
+
+// rows returns the lines of the current tab
+func (mv *mergeView) rows() []diffRow {
+	v := mv.backend.Var()
+	if mv.tab == mergeTabChanges {
+		var rows []diffRow
+		for _, item := range mv.items {
+			mark := "[ ] "
+			if item.take {
+				mark = "[x] "
+			}
+			text := mark + "+ " + displayPath(pathEntry{path: item.path}, false, v) + "  (added to " + item.section
+			if item.after != "" {
+				text += " after " + item.after
+			}
+			text += ")"
+			style := ansiGreen
+			switch {
+			case item.removed:
+				text = mark + "- " + displayPath(pathEntry{path: item.path}, false, v) + "  (removed from " + item.section + ")"
+				style = ansiRed
+			case item.moved:
+				text = mark + "> " + displayPath(pathEntry{path: item.path}, false, v) + "  (moved to the top of " + item.section + ")"
+				if item.after != "" {
+					text = mark + "> " + displayPath(pathEntry{path: item.path}, false, v) + "  (moved in " + item.section + " after " + item.after + ")"
+				}
+				style = ansiBlue
+			}
+			if item.conflict {
+				text += " - conflict: you moved or edited it"
+				style = ansiYellow
+			}
+			rows = append(rows, diffRow{" " + text, style})
+		}
+		if len(rows) == 0 {
+			rows = []diffRow{{" The other changes are already in your edits", ansiGrey}}
+		}
+		return rows
+	}
+
+	var entries []pathEntry
+	switch mv.tab {
+	case mergeTabOriginal:
+		entries = mv.original
+	case mergeTabExternal:
+		entries = mv.external
+	case mergeTabOurs:
+		entries = mv.ours
+	case mergeTabMerged:
+		entries = mv.merged()
+	}
+	var rows []diffRow
+	for _, section := range mv.backend.Sections() {
+		rows = append(rows, diffRow{" " + section, ansiBold})
+		for _, p := range sectionPaths(entries, section) {
+			rows = append(rows, diffRow{"   " + displayPath(pathEntry{path: p}, false, v), ""})
+		}
+	}
+	return rows
+}
+
+// Update handles input for the merge view.
+// Returns nil when closed, with the merged entries and true, or false if cancelled.
+func (mv *mergeView) Update(msg tea.KeyMsg) (*mergeView, []pathEntry, bool) {
+	rows := len(mv.rows())
+	switch msg.String() {
+	case keyUp, keyUpAlt:
+		mv.list.MoveUp()
+	case keyDown, keyDownAlt:
+		mv.list.MoveDown(rows)
+	case keyPgUp, keyPgUpAlt:
+		mv.list.PageUp()
+	case keyPgDown, keyPgDownAlt:
+		mv.list.PageDown(rows)
+	case keyHome, keyHomeAlt:
+		mv.list.Home()
+	case keyEnd, keyEndAlt:
+		mv.list.End(rows)
+	case keySelect, "shift+tab":
+		step := 1
+		if msg.String() == "shift+tab" {
+			step = mergeTabCount - 1
+		}
+		mv.tab = (mv.tab + step) % mergeTabCount
+		mv.list.cursor, mv.list.offset = 0, 0
+		mv.resize()
+	case keyMark:
+		if mv.tab == mergeTabChanges && mv.list.cursor < len(mv.items) {
+			mv.items[mv.list.cursor].take = !mv.items[mv.list.cursor].take
+		}
+	case keyMergeTheirs, keyMergeOurs:
+		for i := range mv.items {
+			mv.items[i].take = msg.String() == keyMergeTheirs
+		}
+	case keyEnter:
+		return nil, mv.merged(), true
+	case keyEsc:
+		return nil, nil, false
+	}
+	return mv, nil, false
+}
+
+// View renders the merge view
+func (mv *mergeView) View(viewWidth int) string {
+	var sb strings.Builder
+	v := mv.backend.Var()
+	title := truncateLine(" "+v.name+" in "+mv.backend.Describe()+" changed since it was loaded", viewWidth)
+	sb.WriteString(ansiYellow + title + ansiReset + "\n")
+	var tabs []string
+	for i, name := range mergeTabNames {
+		if i == mv.tab {
+			tabs = append(tabs, ansiReverse+" "+name+" "+ansiNoReverse)
+		} else {
+			tabs = append(tabs, " "+name+" ")
+		}
+	}
+	sb.WriteString(" " + strings.Join(tabs, "|") + "\n\n")
+
+	rows := mv.rows()
+	start, end := mv.list.VisibleRange(len(rows))
+	scrollbar := mv.list.RenderScrollbar(len(rows))
+	for i := start; i < end; i++ {
+		text := truncateLine(rows[i].text, viewWidth-3)
+		cursor := " "
+		if mv.tab == mergeTabChanges && i == mv.list.cursor && len(mv.items) > 0 {
+			cursor = ">"
+		}
+		line := cursor + text
+		if rows[i].style != "" {
+			line = cursor + rows[i].style + text + ansiReset
+		}
+		if padding := viewWidth - 3 - utf8.RuneCountInString(text); padding > 0 {
+			line += strings.Repeat(" ", padding)
+		}
+		sb.WriteString(line + " " + scrollbar[i-start] + "\n")
+	}
+	for i := end - start; i < mv.list.viewHeight; i++ {
+		scrollChar := " "
+		if i < len(scrollbar) {
+			scrollChar = scrollbar[i]
+		}
+		sb.WriteString(strings.Repeat(" ", viewWidth-1) + scrollChar + "\n")
+	}
+	return sb.String()
+}
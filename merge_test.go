@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// sectionEntries returns registry entries with the given paths in one section
+func sectionEntries(section string, paths ...string) []pathEntry {
+	var entries []pathEntry
+	for _, p := range paths {
+		entries = append(entries, pathEntry{path: p, source: section})
+	}
+	return entries
+}
+
+func TestMergeItems(t *testing.T) {
+	b, _ := newTestRegistry(nil, nil)
+	tests := []struct {
+		name     string
+		original []string
+		external []string
+		ours     func([]pathEntry) []pathEntry
+		want     []mergeItem
+		merged   []string
+	}{
+		{
+			name:     "reordered externally",
+			original: []string{"/s1", "/s2", "/s3"},
+			external: []string{"/s3", "/s1", "/s2"},
+			want:     []mergeItem{{section: "system", path: "/s3", moved: true, take: true}},
+			merged:   []string{"/s3", "/s1", "/s2"},
+		},
+		{
+			name:     "reordered externally after an entry",
+			original: []string{"/s1", "/s2", "/s3"},
+			external: []string{"/s1", "/s3", "/s2"},
+			want:     []mergeItem{{section: "system", path: "/s2", moved: true, after: "/s3", take: true}},
+			merged:   []string{"/s1", "/s3", "/s2"},
+		},
+		{
+			name:     "reordered on both sides the same way",
+			original: []string{"/s1", "/s2", "/s3"},
+			external: []string{"/s3", "/s1", "/s2"},
+			ours: func(paths []pathEntry) []pathEntry {
+				return movePathEntry(paths, 2, 0)
+			},
+			merged: []string{"/s3", "/s1", "/s2"},
+		},
+		{
+			name:     "moved in ours, reordered externally",
+			original: []string{"/s1", "/s2", "/s3"},
+			external: []string{"/s3", "/s1", "/s2"},
+			ours: func(paths []pathEntry) []pathEntry {
+				return movePathEntry(paths, 2, 1)
+			},
+			want:   []mergeItem{{section: "system", path: "/s3", moved: true, conflict: true}},
+			merged: []string{"/s1", "/s3", "/s2"},
+		},
+		{
+			name:     "duplicate added externally",
+			original: []string{"/s1", "/s2"},
+			external: []string{"/s1", "/s2", "/s1"},
+			want:     []mergeItem{{section: "system", path: "/s1", after: "/s2", take: true}},
+			merged:   []string{"/s1", "/s2", "/s1"},
+		},
+		{
+			name:     "duplicate removed externally",
+			original: []string{"/s1", "/s2", "/s1"},
+			external: []string{"/s1", "/s2"},
+			want:     []mergeItem{{section: "system", path: "/s1", removed: true, nth: 1, take: true}},
+			merged:   []string{"/s1", "/s2"},
+		},
+		{
+			name:     "added on both sides",
+			original: []string{"/s1"},
+			external: []string{"/s1", "/s2"},
+			ours: func(paths []pathEntry) []pathEntry {
+				return append(paths, pathEntry{path: "/s2", source: "system", added: true})
+			},
+			merged: []string{"/s1", "/s2"},
+		},
+		{
+			name:     "removed on both sides",
+			original: []string{"/s1", "/s2"},
+			external: []string{"/s1"},
+			ours: func(paths []pathEntry) []pathEntry {
+				paths[1].deleted = true
+				return paths
+			},
+			merged: []string{"/s1"},
+		},
+		{
+			name:     "removed externally, edited in ours",
+			original: []string{"/s1", "/s2"},
+			external: []string{"/s1"},
+			ours: func(paths []pathEntry) []pathEntry {
+				paths[1].modified = true
+				return paths
+			},
+			want:   []mergeItem{{section: "system", path: "/s2", removed: true, conflict: true}},
+			merged: []string{"/s1", "/s2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := sectionEntries("system", tt.original...)
+			external := sectionEntries("system", tt.external...)
+			ours := slices.Clone(original)
+			if tt.ours != nil {
+				ours = tt.ours(ours)
+			}
+			items := mergeItems(b, original, external, ours)
+			if !slices.Equal(items, tt.want) {
+				t.Errorf("mergeItems() = %+v, want %+v", items, tt.want)
+			}
+			merged := mergedPaths(ours, external, items, pathVariable)
+			if got := sectionPaths(merged, "system"); !slices.Equal(got, tt.merged) {
+				t.Errorf("merged = %q, want %q", got, tt.merged)
+			}
+		})
+	}
+}
+
+func TestMergedPathsNotTaken(t *testing.T) {
+	// Changes that are not taken are undone in the merged entries, relative to the
+	// external value they are saved over
+	b, _ := newTestRegistry(nil, nil)
+	original := sectionEntries("system", "/s1", "/s2", "/s3")
+	external := sectionEntries("system", "/s3", "/s1", "/s4")
+	ours := slices.Clone(original)
+	items := mergeItems(b, original, external, ours)
+	for i := range items {
+		items[i].take = false
+	}
+	merged := mergedPaths(ours, external, items, pathVariable)
+	if got, want := sectionPaths(merged, "system"), []string{"/s1", "/s2", "/s3"}; !slices.Equal(got, want) {
+		t.Errorf("merged = %q, want %q", got, want)
+	}
+	for _, p := range merged {
+		if want := p.path == "/s2"; p.added != want {
+			t.Errorf("%s added = %v, want %v", p.path, p.added, want)
+		}
+		if want := p.path == "/s4"; p.deleted != want {
+			t.Errorf("%s deleted = %v, want %v", p.path, p.deleted, want)
+		}
+	}
+}
+
+func TestPersistPathsExternalChange(t *testing.T) {
+	t.Setenv("PATHED_STATE_DIR", t.TempDir())
+	b, store := newTestRegistry([]string{"/s1"}, []string{"/u1"})
+	paths, _ := b.Load()
+	loaded := fingerprint(b, paths)
+
+	// Another program changes the store after the editor loaded it
+	store.values[regKey{"user", "Path"}] = regValue{data: pathVariable.join([]string{"/u1", "/x"}), expand: true}
+	paths = insertPathEntry(paths, pathEntry{path: "/u2", source: "user", added: true})
+	if err := persistPaths(b, paths, loaded); !errors.Is(err, errStoreChanged) {
+		t.Fatalf("persistPaths() = %v, want errStoreChanged", err)
+	}
+	if got, want := storedPaths(store, "user"), []string{"/u1", "/x"}; !slices.Equal(got, want) {
+		t.Errorf("user = %q, want %q", got, want)
+	}
+	if backups, _ := loadBackups(); len(backups) != 0 {
+		t.Errorf("got %d backups of a refused save, want 0", len(backups))
+	}
+
+	// Against the current fingerprint, the save goes through
+	current, _ := b.Load()
+	if err := persistPaths(b, paths, fingerprint(b, current)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := storedPaths(store, "user"), []string{"/u1", "/u2"}; !slices.Equal(got, want) {
+		t.Errorf("user = %q, want %q", got, want)
+	}
+}
+
+// failingRegistry is a memRegistry that can no longer be read
+type failingRegistry struct {
+	*memRegistry
+}
+
+func (r failingRegistry) get(section, name string) (regValue, error) {
+	return regValue{}, errors.New("access denied")
+}
+
+func TestSaveAndQuitReadError(t *testing.T) {
+	m := newTestModel(t, []string{"/s1"}, []string{"/u1"})
+	rb := m.backend.(registryBackend)
+	rb.store = failingRegistry{rb.store.(*memRegistry)}
+	m.backend = rb
+
+	next, cmd := m.Update(saveAndQuitMsg{saveType: 2})
+	m = next.(model)
+	if cmd != nil || m.saveChanges {
+		t.Error("quit to persist although the store can't be read")
+	}
+	if m.prompt == nil {
+		t.Error("no prompt reporting the error")
+	}
+}
+
+func TestSaveAndQuitExternalChange(t *testing.T) {
+	m := newTestModel(t, []string{"/s1", "/s2", "/s3"}, nil)
+	store := m.backend.(registryBackend).store.(*memRegistry)
+	store.values[regKey{"system", "Path"}] = regValue{data: pathVariable.join([]string{"/s3", "/s1", "/s2"}), expand: true}
+
+	next, cmd := m.Update(saveAndQuitMsg{saveType: 2})
+	m = next.(model)
+	if cmd != nil || m.mergeView == nil {
+		t.Fatal("no merge view of the external change")
+	}
+	if want := []mergeItem{{section: "system", path: "/s3", moved: true, take: true}}; !slices.Equal(m.mergeView.items, want) {
+		t.Errorf("items = %+v, want %+v", m.mergeView.items, want)
+	}
+}
@@ -1,38 +1,76 @@
 package main
 
-import tea "github.com/charmbracelet/bubbletea"
+import (
+	"io"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
 
 type model struct {
 	paths        []pathEntry
-	originalPath string // PATH at startup, for "don't save" case
+	originalPath string // value at startup, for "don't save" case
 	list         listState
 	viewWidth    int
+	height       int // lines available to the list, the shadow pane and full-screen views
 	prompt       *prompt
-	browser      *browser  // directory browser for editing paths
-	helpView     *helpView // help screen
-	saveChanges  bool      // true if user chose to save changes
-	registryMode bool      // true when reading from Windows registry (system/user split)
-	elevated     bool      // true if running with administrator privileges (Windows)
-}
-
-func initialModel(registryMode bool) model {
-	var paths []pathEntry
-	if registryMode && supportsRegistry {
-		paths = loadPathsFromRegistry()
-	} else {
-		paths = loadPathsFromEnv()
+	browser      *browser    // directory browser for editing paths
+	editor       *lineEditor // line editor for typing paths directly
+	helpView     *helpView   // help screen
+	varPicker    *varPicker  // list of variables to switch to
+	diffView     *diffView   // diff of the pending changes
+	mergeView    *mergeView  // external changes to merge before saving
+	history      history     // undo/redo stacks for edits in the main list
+	searching    bool        // true while typing a search query
+	searchQuery  string      // current search query (case-insensitive substring)
+	searchOrigin int         // index of the entry under the cursor when the search started
+	filtered     bool        // true when only entries matching the search query are shown
+	visual       bool        // true while selecting a range with v
+	visualAnchor int         // index of the entry where the range selection started
+	pick         *pickState  // entries moved to the other section while their position is picked
+	pendingKey   string      // first key of a two-key command (dd, yy)
+	register     []string    // paths yanked with yy or dd, pasted with p and P
+	clipboard    io.Writer   // terminal to send OSC 52 clipboard sequences to, or nil
+	showExpanded bool        // true to show expanded paths next to entries with variable references
+	pane         int         // pane shown below the list (paneNone, paneShadows, paneWhich, paneDetail)
+	detail       listState   // scroll position of the detail pane
+	detailFor    int         // index of the entry the detail pane scroll position belongs to
+	typingWhich  bool        // true while typing a command name to look up
+	whichInput   string      // command name being typed
+	whichCommand string      // command resolved in the which pane
+	execs        execCache   // executables found in PATH directories, scanned on demand
+	infos        infoCache   // metadata of PATH directories, read on demand
+	analyses     *analyses   // audit and shadow analysis of the entries, until they change
+	saveChanges  bool        // true if user chose to save changes
+	backend      backend     // where entries are loaded from and saved to
+	elevated     bool        // true if running with administrator privileges (Windows)
+	base         []pathEntry // entries as loaded, the common ancestor when merging external changes
+	fingerprint  string      // fingerprint of the loaded value, to notice external changes
+}
+
+func initialModel(b backend) (model, error) {
+	paths, err := b.Load()
+	if err != nil {
+		return model{}, err
 	}
 
 	return model{
 		paths:        paths,
-		originalPath: buildPathString(paths),
+		originalPath: buildPathString(paths, b.Var()),
+		base:         slices.Clone(paths),
+		fingerprint:  fingerprint(b, paths),
 		list: listState{
 			viewHeight: 20,
 		},
 		viewWidth:    80,
-		registryMode: registryMode && supportsRegistry,
+		height:       20,
+		execs:        execCache{},
+		infos:        infoCache{},
+		analyses:     &analyses{},
+		backend:      b,
 		elevated:     isElevated(),
-	}
+		showExpanded: hasSection(b, "system"), // registry values commonly use %VAR% references
+	}, nil
 }
 
 // hasModifications returns true if any path entry has been modified, deleted, or added
@@ -48,3 +86,66 @@ func (m model) hasModifications() bool {
 func (m model) Init() tea.Cmd {
 	return nil
 }
+
+// analyses memoizes the audit and the shadow analysis of the entries, which every
+// redraw needs, until the entries change. Copies of a model share it.
+type analyses struct {
+	paths    []pathEntry     // entries the results belong to
+	valid    bool            // false until computed, and after a rescan
+	findings []auditFinding  // nil until audited
+	audited  bool            // true once findings is set
+	shadows  *shadowAnalysis // nil until analyzed
+}
+
+// sameAnalysis returns true if the analyses of two entries are the same
+func sameAnalysis(a, b pathEntry) bool {
+	return a.path == b.path && a.source == b.source && a.deleted == b.deleted && a.exists == b.exists
+}
+
+// sync drops the results if m's entries changed since they were computed, along with
+// the cached directory data of entries that weren't there before, so that an edited,
+// pasted or restored entry is read again
+func (a *analyses) sync(m model) {
+	if a.valid && slices.EqualFunc(a.paths, m.paths, sameAnalysis) {
+		return
+	}
+	if a.valid {
+		for _, p := range m.paths {
+			if !slices.ContainsFunc(a.paths, func(q pathEntry) bool { return q.path == p.path }) {
+				m.execs.drop(p.path)
+				m.infos.drop(p.path)
+			}
+		}
+	}
+	*a = analyses{paths: slices.Clone(m.paths), valid: true}
+}
+
+// auditPaths returns the audit findings of the entries
+func (m model) auditPaths() []auditFinding {
+	m.analyses.sync(m)
+	if !m.analyses.audited {
+		m.analyses.findings = auditPaths(m.paths, m.infos, m.backend.Var())
+		m.analyses.audited = true
+	}
+	return m.analyses.findings
+}
+
+// analyzeShadows returns the shadow analysis of the entries
+func (m model) analyzeShadows() *shadowAnalysis {
+	m.analyses.sync(m)
+	if m.analyses.shadows == nil {
+		m.analyses.shadows = analyzeShadows(m.paths, m.execs)
+	}
+	return m.analyses.shadows
+}
+
+// rescan reads the entries' directories again, for changes made on disk since they
+// were first read: whether they exist, their metadata and their executables
+func (m *model) rescan() {
+	clear(m.execs)
+	clear(m.infos)
+	for i, p := range m.paths {
+		m.paths[i].exists = m.backend.Var().exists(p.path)
+	}
+	m.analyses.valid = false
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Panes shown below the main list
+const (
+	paneNone    = iota
+	paneShadows // commands the cursor entry hides or is hidden by
+	paneWhich   // where a command resolves under the edited PATH
+	paneDetail  // metadata and executables of the cursor entry
+)
+
+// paneMaxHeight is the most lines a pane takes below the list
+const paneMaxHeight = 10
+
+// paneHeight returns the lines taken by the pane below the list
+func (m model) paneHeight() int {
+	if m.pane == paneNone {
+		return 0
+	}
+	return min(paneMaxHeight, m.height/2)
+}
+
+// resizeList fits the list into the lines not taken by the pane
+func (m *model) resizeList() {
+	m.list.SetViewHeight(max(1, m.height-m.paneHeight()), len(m.rows()))
+	m.detail.viewHeight = max(1, m.paneHeight()-1) // below the pane's title line
+}
+
+// togglePane shows the given pane, or hides it if it is already shown
+func (m *model) togglePane(pane int) {
+	if m.pane == pane {
+		m.pane = paneNone
+	} else {
+		m.pane = pane
+	}
+	m.resizeList()
+}
+
+// renderPane returns the pane below the list, padded or cut to height lines
+func (m model) renderPane(height int) string {
+	var lines []string
+	switch v := m.backend.Var(); {
+	case (m.pane == paneShadows || m.pane == paneWhich) && !v.commands:
+		lines = []string{" " + v.name + " holds no commands - shadows and which apply to PATH only"}
+	case m.pane == paneShadows:
+		lines = m.shadowPaneLines()
+	case m.pane == paneWhich:
+		lines = m.whichPaneLines()
+	case m.pane == paneDetail:
+		return m.renderDetailPane()
+	}
+	if len(lines) > height {
+		more := len(lines) - height + 1
+		lines = append(lines[:height-1], fmt.Sprintf("   ... %d more", more))
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// renderDetailPane returns the detail pane: a title line and the scrollable details
+// of the entry under the cursor, with a scrollbar like the main list
+func (m model) renderDetailPane() string {
+	var b strings.Builder
+	title := " Details"
+	if idx := m.cursorIndex(); idx >= 0 {
+		title += ": " + m.paths[idx].path
+	}
+	b.WriteString(ansiBold + truncateLine(title+"  ([/]: scroll, {/}: page)", m.viewWidth) + ansiReset + "\n")
+
+	lines := m.detailPaneLines()
+	detail := m.detail
+	if m.cursorIndex() != m.detailFor {
+		detail.ScrollHome() // the scroll position belongs to another entry
+	}
+	start, end := detail.VisibleRange(len(lines))
+	scrollbar := detail.RenderScrollbar(len(lines))
+	for i := 0; i < detail.viewHeight; i++ {
+		line := ""
+		if start+i < end {
+			line = truncateLine(" "+lines[start+i], m.viewWidth-2)
+		}
+		if padding := m.viewWidth - 2 - len([]rune(line)); padding > 0 {
+			line += strings.Repeat(" ", padding)
+		}
+		b.WriteString(line + " " + scrollbar[i] + "\n")
+	}
+	return b.String()
+}
+
+// shadowPaneLines describes which commands the entry under the cursor hides in
+// later entries, and which of its commands earlier entries hide
+func (m model) shadowPaneLines() []string {
+	idx := m.cursorIndex()
+	if idx < 0 {
+		return []string{" No entry selected"}
+	}
+	entry := m.paths[idx]
+	analysis := m.analyzeShadows()
+	shadows, shadowedBy := analysis.shadows(idx), analysis.shadowedBy(idx)
+	lines := []string{ansiBold + truncateLine(fmt.Sprintf(" Commands in %s: %d executables, %d hide later entries, %d hidden",
+		entry.path, len(analysis.execs[idx]), len(shadows), len(shadowedBy)), m.viewWidth) + ansiReset}
+	switch {
+	case entry.deleted:
+		lines = append(lines, " Entry is marked for deletion and not on PATH")
+	case !entry.exists:
+		lines = append(lines, " Directory does not exist")
+	case analysis.execs[idx] == nil && len(m.execs.scan(entry.path)) > 0:
+		lines = append(lines, " Directory appears earlier in PATH - its commands resolve there")
+	case len(shadows) == 0 && len(shadowedBy) == 0:
+		lines = append(lines, " No commands shared with other entries")
+	}
+	for _, c := range shadows {
+		lines = append(lines, truncateLine(fmt.Sprintf("   %-20s hides %s", c.command, m.entryPaths(c.entries)), m.viewWidth))
+	}
+	for _, c := range shadowedBy {
+		lines = append(lines, ansiYellow+truncateLine(fmt.Sprintf("   %-20s hidden by %s", c.command, m.entryPaths(c.entries)), m.viewWidth)+ansiReset)
+	}
+	return lines
+}
+
+// whichPaneLines lists every executable the looked-up command resolves to under
+// the edited PATH, in order, with the winner highlighted
+func (m model) whichPaneLines() []string {
+	matches := m.analyzeShadows().which(m.whichCommand)
+	lines := []string{ansiBold + truncateLine(fmt.Sprintf(" which %s: %d matches", m.whichCommand, len(matches)), m.viewWidth) + ansiReset}
+	if len(matches) == 0 {
+		lines = append(lines, " Not found on the edited PATH")
+	}
+	for i, match := range matches {
+		file := filepath.Join(expandPath(m.paths[match.index].path), match.file)
+		if i == 0 {
+			lines = append(lines, ansiGreen+truncateLine("   > "+file+"  (wins)", m.viewWidth)+ansiReset)
+		} else {
+			lines = append(lines, truncateLine("     "+file+"  (hidden)", m.viewWidth))
+		}
+	}
+	return lines
+}
+
+// entryPaths joins the paths of the given entries
+func (m model) entryPaths(indices []int) string {
+	parts := make([]string, len(indices))
+	for i, idx := range indices {
+		parts[i] = m.paths[idx].path
+	}
+	return strings.Join(parts, ", ")
+}
@@ -1,9 +1,6 @@
 package main
 
-import (
-	"os"
-	"strings"
-)
+import "os"
 
 // dirExists checks if a directory exists
 func dirExists(path string) bool {
@@ -11,25 +8,68 @@ func dirExists(path string) bool {
 	return err == nil && info.IsDir()
 }
 
-// buildPathString constructs the PATH string from entries (excluding deleted)
-func buildPathString(paths []pathEntry) string {
+// buildPathString constructs the variable's value from entries (excluding deleted)
+func buildPathString(paths []pathEntry, v pathVar) string {
 	var parts []string
 	for _, p := range paths {
 		if !p.deleted {
 			parts = append(parts, p.path)
 		}
 	}
-	return strings.Join(parts, string(os.PathListSeparator))
+	return v.join(parts)
 }
 
-// loadPathsFromEnv reads PATH from the process environment (no system/user distinction)
-func loadPathsFromEnv() []pathEntry {
+// loadPathsFromEnv reads a variable from the process environment (no system/user distinction).
+// Empty segments are kept: for PATH the shell treats them as the current directory, so
+// they are shown (and flagged by the audit) rather than silently dropped.
+func loadPathsFromEnv(v pathVar) []pathEntry {
 	var entries []pathEntry
-	pathEnv := os.Getenv("PATH")
-	for _, p := range strings.Split(pathEnv, string(os.PathListSeparator)) {
-		if p != "" {
-			entries = append(entries, pathEntry{path: p, source: "", exists: dirExists(p)})
-		}
+	for _, p := range v.split(os.Getenv(v.name)) {
+		entries = append(entries, pathEntry{path: p, source: "", exists: v.exists(p)})
 	}
 	return entries
 }
+
+// pathKey returns the form entries are compared in: expanded and normalized, so
+// "$HOME/bin", "~/bin" and "/home/me/bin" are the same entry
+func pathKey(path string) string {
+	return normalizePath(expandPath(path))
+}
+
+// findDuplicates reports for each entry whether an earlier entry has the same expanded,
+// normalized path, so "$HOME/bin" and "/home/me/bin" are duplicates.
+// In sectioned stores duplicates are only considered within the same source; in env mode
+// all entries share the empty source, so duplicates are found globally.
+func findDuplicates(paths []pathEntry) []bool {
+	seen := make(map[string]map[string]bool) // source -> normalized path -> seen
+	duplicates := make([]bool, len(paths))
+	for i, p := range paths {
+		if seen[p.source] == nil {
+			seen[p.source] = make(map[string]bool)
+		}
+		normalizedPath := pathKey(p.path)
+		duplicates[i] = seen[p.source][normalizedPath]
+		seen[p.source][normalizedPath] = true
+	}
+	return duplicates
+}
+
+// cleanReasons returns, for each entry, why the clean command would mark it for deletion
+// ("missing" or "duplicate"), or "" to keep it. The missing and duplicates flags select
+// which checks are applied. Entries inherited from the environment, which b doesn't
+// save, are kept.
+func cleanReasons(b backend, paths []pathEntry, missing, duplicates bool) []string {
+	reasons := make([]string, len(paths))
+	dups := findDuplicates(paths)
+	for i, p := range paths {
+		if inherited(b, p) {
+			continue
+		}
+		if missing && !p.exists {
+			reasons[i] = "missing"
+		} else if duplicates && dups[i] {
+			reasons[i] = "duplicate"
+		}
+	}
+	return reasons
+}
@@ -2,7 +2,19 @@
 
 package main
 
-const supportsRegistry = false
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+const (
+	supportsRegistry = false
+	supportsProfile  = true // shell profile mode (Linux/macOS)
+)
 
 // isElevated is a stub for non-Windows platforms (always returns true to suppress warnings)
 func isElevated() bool {
@@ -15,13 +27,65 @@ func normalizePath(path string) string {
 	return path
 }
 
-// loadPathsFromRegistry is a stub for non-Windows platforms.
-// It should never be called since supportsRegistry is false.
-func loadPathsFromRegistry() []pathEntry {
-	return loadPathsFromEnv()
+// commandName returns the command a file in a PATH directory provides: any regular
+// file with an execute bit set, under its own name. Unix has no extension ranking.
+func commandName(name string, info fs.FileInfo) (cmd string, rank int, ok bool) {
+	return name, 0, info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
+
+// fileOwner returns the name of the user owning a file, or its uid if the name is unknown
+func fileOwner(_ string, info fs.FileInfo) string {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	uid := strconv.FormatUint(uint64(stat.Uid), 10)
+	if u, err := user.LookupId(uid); err == nil {
+		return u.Username
+	}
+	return uid
+}
+
+// dirWritable reports whether the current user may create files in a directory
+func dirWritable(dir string) bool {
+	const wOK = 2 // W_OK from unistd.h
+	return syscall.Access(dir, wOK) == nil
+}
+
+// worldWritable reports whether every user may create files in a directory
+func worldWritable(_ string, info fs.FileInfo) bool {
+	return info.Mode().Perm()&0o002 != 0
+}
+
+// ownedByOther reports whether a file is owned by someone other than the current
+// user and root
+func ownedByOther(_ string, info fs.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Uid != 0 && int(stat.Uid) != os.Getuid()
+}
+
+// systemDirs returns the directories holding the operating system's commands
+func systemDirs() []string {
+	return []string{"/usr/bin", "/bin", "/usr/sbin", "/sbin"}
+}
+
+// tempDirs returns the temporary directories
+func tempDirs() []string {
+	return []string{os.TempDir(), "/tmp", "/var/tmp", "/dev/shm"}
 }
 
-// savePaths is a stub for non-Windows platforms.
-func savePaths(_ []pathEntry) error {
-	return nil
+// commandKey returns the command name a typed command is looked up by
+func commandKey(command string) string {
+	return command
 }
+
+// winRegistry is a stub for non-Windows platforms.
+// It should never be used since supportsRegistry is false.
+type winRegistry struct{}
+
+var errNoRegistry = errors.New("the registry is only available on Windows")
+
+func (winRegistry) name() string                         { return "Windows registry" }
+func (winRegistry) changed()                             {}
+func (winRegistry) get(string, string) (regValue, error) { return regValue{}, errNoRegistry }
+func (winRegistry) set(string, string, regValue) error   { return errNoRegistry }
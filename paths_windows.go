@@ -5,6 +5,10 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 	"unsafe"
@@ -13,7 +17,10 @@ import (
 	"golang.org/x/sys/windows/registry"
 )
 
-const supportsRegistry = true
+const (
+	supportsRegistry = true
+	supportsProfile  = false // shell profile mode (Linux/macOS)
+)
 
 // isElevated checks if the current process is running with administrator privileges
 func isElevated() bool {
@@ -44,128 +51,221 @@ func normalizePath(path string) string {
 	return strings.ToLower(path)
 }
 
-// loadPathsFromRegistry reads PATH from Windows registry with system/user distinction
-func loadPathsFromRegistry() []pathEntry {
-	var entries []pathEntry
-
-	// System PATH from HKLM
-	sysKey, err := registry.OpenKey(registry.LOCAL_MACHINE,
-		`SYSTEM\CurrentControlSet\Control\Session Manager\Environment`,
-		registry.QUERY_VALUE)
-	if err == nil {
-		defer sysKey.Close()
-		sysPath, _, err := sysKey.GetStringValue("Path")
-		if err == nil {
-			for _, p := range strings.Split(sysPath, ";") {
-				if p != "" {
-					entries = append(entries, pathEntry{path: p, source: "system", exists: dirExists(p)})
-				}
-			}
-		}
+// commandName returns the command a file in a PATH directory provides: files with an
+// extension listed in PATHEXT, by their name without the extension. rank is the
+// extension's position in PATHEXT, which decides between python.exe and python.bat.
+func commandName(name string, info fs.FileInfo) (cmd string, rank int, ok bool) {
+	if info.IsDir() {
+		return "", 0, false
+	}
+	ext := filepath.Ext(name)
+	rank = slices.IndexFunc(pathExts(), func(e string) bool { return strings.EqualFold(e, ext) })
+	if ext == "" || rank == -1 {
+		return "", 0, false
 	}
+	return strings.ToLower(strings.TrimSuffix(name, ext)), rank, true
+}
 
-	// User PATH from HKCU
-	userKey, err := registry.OpenKey(registry.CURRENT_USER,
-		`Environment`,
-		registry.QUERY_VALUE)
-	if err == nil {
-		defer userKey.Close()
-		userPath, _, err := userKey.GetStringValue("Path")
-		if err == nil {
-			for _, p := range strings.Split(userPath, ";") {
-				if p != "" {
-					entries = append(entries, pathEntry{path: p, source: "user", exists: dirExists(p)})
-				}
-			}
-		}
+// fileOwner returns the account owning a file as DOMAIN\name, or its SID if the
+// account is unknown
+func fileOwner(path string, _ fs.FileInfo) string {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return ""
 	}
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return ""
+	}
+	account, domain, _, err := owner.LookupAccount("")
+	if err != nil {
+		return owner.String()
+	}
+	return domain + `\` + account
+}
 
-	return entries
+// dirWritable reports whether the current user may create files in a directory.
+// Opening the directory for FILE_WRITE_DATA (add file) runs the access check
+// without creating anything.
+func dirWritable(dir string) bool {
+	name, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return false
+	}
+	h, err := windows.CreateFile(name, windows.FILE_WRITE_DATA,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return false
+	}
+	windows.CloseHandle(h)
+	return true
 }
 
-// savePaths writes the modified paths back to the Windows registry
-// Only writes to a registry key if the value has actually changed
-func savePaths(paths []pathEntry) error {
-	var systemPaths, userPaths []string
-	for _, p := range paths {
-		if p.deleted {
+// worldWritable reports whether the DACL of a directory lets Everyone, Authenticated
+// Users or Users create files in it. A missing (NULL) DACL grants everyone full access.
+func worldWritable(path string, _ fs.FileInfo) bool {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return false
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return false
+	}
+	if dacl == nil {
+		return true
+	}
+	broad := wellKnownSids(windows.WinWorldSid, windows.WinAuthenticatedUserSid, windows.WinBuiltinUsersSid)
+	const write = windows.FILE_WRITE_DATA | windows.FILE_APPEND_DATA | windows.GENERIC_WRITE | windows.GENERIC_ALL
+	const inheritOnly = 0x08 // INHERIT_ONLY_ACE: applies to children, not the directory itself
+	for i := uint32(0); i < uint32(dacl.AceCount); i++ {
+		var ace *windows.ACCESS_ALLOWED_ACE
+		if windows.GetAce(dacl, i, &ace) != nil || ace.Header.AceType != windows.ACCESS_ALLOWED_ACE_TYPE {
 			continue
 		}
-		if p.source == "system" {
-			systemPaths = append(systemPaths, p.path)
-		} else {
-			userPaths = append(userPaths, p.path)
+		if ace.Header.AceFlags&inheritOnly != 0 || ace.Mask&write == 0 {
+			continue
+		}
+		sid := (*windows.SID)(unsafe.Pointer(&ace.SidStart))
+		if slices.ContainsFunc(broad, sid.Equals) {
+			return true
 		}
 	}
+	return false
+}
 
-	newSystemPath := strings.Join(systemPaths, ";")
-	newUserPath := strings.Join(userPaths, ";")
+// ownedByOther reports whether a file is owned by an account other than the current
+// user, SYSTEM, Administrators and TrustedInstaller
+func ownedByOther(path string, _ fs.FileInfo) bool {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return false
+	}
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return false
+	}
+	trusted := wellKnownSids(windows.WinLocalSystemSid, windows.WinBuiltinAdministratorsSid)
+	if sid, err := windows.StringToSid(trustedInstallerSid); err == nil {
+		trusted = append(trusted, sid)
+	}
+	if user, err := windows.GetCurrentProcessToken().GetTokenUser(); err == nil {
+		trusted = append(trusted, user.User.Sid)
+	}
+	return !slices.ContainsFunc(trusted, owner.Equals)
+}
+
+// trustedInstallerSid is the service SID of the account that owns Windows system files
+const trustedInstallerSid = "S-1-5-80-956008885-3418522649-1831038044-1853292631-2271478464"
 
-	// Try to write system PATH if changed
-	if err := saveSystemPath(newSystemPath); err != nil {
-		return err
+// wellKnownSids returns the SIDs of well-known accounts, skipping any that fail
+func wellKnownSids(types ...windows.WELL_KNOWN_SID_TYPE) []*windows.SID {
+	var sids []*windows.SID
+	for _, t := range types {
+		if sid, err := windows.CreateWellKnownSid(t); err == nil {
+			sids = append(sids, sid)
+		}
 	}
+	return sids
+}
 
-	// Write user PATH if changed
-	if err := saveUserPath(newUserPath); err != nil {
-		return err
+// systemDirs returns the directories holding the operating system's commands
+func systemDirs() []string {
+	root := os.Getenv("SystemRoot")
+	if root == "" {
+		root = `C:\Windows`
 	}
+	return []string{root + `\System32`, root, root + `\System32\Wbem`}
+}
 
-	// Notify other applications that environment has changed
-	broadcastEnvironmentChange()
-	return nil
+// tempDirs returns the temporary directories
+func tempDirs() []string {
+	root := os.Getenv("SystemRoot")
+	if root == "" {
+		root = `C:\Windows`
+	}
+	return []string{os.TempDir(), os.Getenv("TMP"), root + `\Temp`}
+}
+
+// commandKey returns the command name a typed command is looked up by: command
+// names are case-insensitive, and a PATHEXT extension may be typed or left out
+func commandKey(command string) string {
+	ext := filepath.Ext(command)
+	if ext != "" && slices.ContainsFunc(pathExts(), func(e string) bool { return strings.EqualFold(e, ext) }) {
+		command = strings.TrimSuffix(command, ext)
+	}
+	return strings.ToLower(command)
 }
 
-// writeRegistryPathIfChanged compares current value and writes if different
-func writeRegistryPathIfChanged(key registry.Key, newPath, desc string) error {
-	currentPath, _, _ := key.GetStringValue("Path")
-	if currentPath == newPath {
-		return nil
+// pathExts returns the executable extensions from PATHEXT, with the Windows default
+func pathExts() []string {
+	pathExt := os.Getenv("PATHEXT")
+	if pathExt == "" {
+		pathExt = ".COM;.EXE;.BAT;.CMD"
 	}
-	if err := key.SetStringValue("Path", newPath); err != nil {
-		if errors.Is(err, syscall.ERROR_ACCESS_DENIED) {
-			return fmt.Errorf("access denied: run as Administrator to modify %s PATH", desc)
+	var exts []string
+	for _, ext := range strings.Split(pathExt, ";") {
+		if ext != "" {
+			exts = append(exts, ext)
 		}
-		return fmt.Errorf("failed to write %s PATH: %w", desc, err)
 	}
-	return nil
+	return exts
+}
+
+// winRegistry is the registryStore for the real Windows registry
+type winRegistry struct{}
+
+// registryKey returns the root key and subkey holding a section's Path value
+func registryKey(section string) (registry.Key, string) {
+	if section == "system" {
+		return registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\Session Manager\Environment`
+	}
+	return registry.CURRENT_USER, `Environment`
 }
 
-// saveSystemPath writes the system PATH if it has changed
-func saveSystemPath(newPath string) error {
-	const subKey = `SYSTEM\CurrentControlSet\Control\Session Manager\Environment`
+func (winRegistry) name() string { return "Windows registry" }
+func (winRegistry) changed()     { broadcastEnvironmentChange() }
 
-	// Try to open with write access
-	sysKey, err := registry.OpenKey(registry.LOCAL_MACHINE, subKey, registry.QUERY_VALUE|registry.SET_VALUE)
+// get reads a section's value with its type. GetStringValue returns the raw
+// text without expanding %VAR% references, for both REG_SZ and REG_EXPAND_SZ.
+func (winRegistry) get(section, name string) (regValue, error) {
+	root, subKey := registryKey(section)
+	key, err := registry.OpenKey(root, subKey, registry.QUERY_VALUE)
 	if err != nil {
-		if errors.Is(err, syscall.ERROR_ACCESS_DENIED) {
-			// No write access - check if we even need to write
-			sysKeyRO, errRO := registry.OpenKey(registry.LOCAL_MACHINE, subKey, registry.QUERY_VALUE)
-			if errRO == nil {
-				currentPath, _, _ := sysKeyRO.GetStringValue("Path")
-				sysKeyRO.Close()
-				if currentPath == newPath {
-					return nil // No change needed, skip
-				}
-			}
-			return fmt.Errorf("access denied: run as Administrator to modify system PATH")
-		}
-		return fmt.Errorf("failed to open system PATH key: %w", err)
+		return regValue{}, err
 	}
-	defer sysKey.Close()
+	defer key.Close()
 
-	return writeRegistryPathIfChanged(sysKey, newPath, "system")
+	data, valType, err := key.GetStringValue(name)
+	if errors.Is(err, registry.ErrNotExist) {
+		return regValue{}, nil
+	}
+	if err != nil {
+		return regValue{}, err
+	}
+	return regValue{data: data, expand: valType == registry.EXPAND_SZ}, nil
 }
 
-// saveUserPath writes the user PATH if it has changed
-func saveUserPath(newPath string) error {
-	userKey, err := registry.OpenKey(registry.CURRENT_USER, `Environment`, registry.QUERY_VALUE|registry.SET_VALUE)
+// set writes a section's value with the given type
+func (winRegistry) set(section, name string, v regValue) error {
+	root, subKey := registryKey(section)
+	key, err := registry.OpenKey(root, subKey, registry.SET_VALUE)
+	if err == nil {
+		defer key.Close()
+		if v.expand {
+			err = key.SetExpandStringValue(name, v.data)
+		} else {
+			err = key.SetStringValue(name, v.data)
+		}
+	}
+	if errors.Is(err, syscall.ERROR_ACCESS_DENIED) {
+		return fmt.Errorf("access denied: run as Administrator to modify %s %s", section, name)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to open user PATH key: %w", err)
+		return fmt.Errorf("failed to write %s %s: %w", section, name, err)
 	}
-	defer userKey.Close()
-
-	return writeRegistryPathIfChanged(userKey, newPath, "user")
+	return nil
 }
 
 // broadcastEnvironmentChange notifies all windows that environment variables have changed.
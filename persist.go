@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// withInheritedPaths returns the user entries followed by the rest of the process value
+// of v as inherited system entries. User entries come first because they are prepended.
+func withInheritedPaths(userPaths []string, v pathVar) []pathEntry {
+	// The environment already contains the user entries if the shell has loaded them
+	remaining := make(map[string]int)
+	for _, p := range userPaths {
+		remaining[expandPath(p)]++
+	}
+
+	var entries []pathEntry
+	for _, p := range userPaths {
+		entries = append(entries, pathEntry{path: p, source: "user", exists: v.exists(p)})
+	}
+	for _, p := range loadPathsFromEnv(v) {
+		if remaining[p.path] > 0 {
+			remaining[p.path]--
+			continue
+		}
+		p.source = "system"
+		entries = append(entries, p)
+	}
+	return entries
+}
+
+// updateFile rewrites file with the result of update applied to its content.
+// A missing file is treated as empty. The file is only written if the content changed,
+// and is replaced atomically so a failed write can't truncate it.
+func updateFile(file string, update func(content string) string) error {
+	// Write through symlinks (e.g. dotfiles managed in a repository)
+	if resolved, err := filepath.EvalSymlinks(file); err == nil {
+		file = resolved
+	}
+
+	perm := os.FileMode(0o644)
+	content, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	if info, err := os.Stat(file); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	newContent := update(string(content))
+	if newContent == string(content) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(file), err)
+	}
+	tmp := file + ".pathed.tmp"
+	if err := os.WriteFile(tmp, []byte(newContent), perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Markers delimiting the block pathed manages inside a shell startup file. Variables
+// other than PATH get their own block, named after the variable.
+const (
+	profileBlockStart = "# >>> pathed >>>"
+	profileBlockEnd   = "# <<< pathed <<<"
+	profileBlockNote  = "# Managed by pathed - changes inside this block are overwritten."
+)
+
+// profileMarkers returns the start and end markers of v's managed block
+func profileMarkers(v pathVar) (start, end string) {
+	if v.isPath() {
+		return profileBlockStart, profileBlockEnd
+	}
+	return "# >>> pathed " + v.name + " >>>", "# <<< pathed " + v.name + " <<<"
+}
+
+// profileTail returns what follows the user entries in v's export line: the inherited
+// value. Unless an empty segment is meaningful for v, the separator is only added when
+// the variable is set, so an unset variable doesn't gain an empty entry.
+func profileTail(v pathVar) string {
+	if v.isPath() || v.keepEmpty {
+		return v.separator + "$" + v.name + `"`
+	}
+	return "${" + v.name + ":+" + v.separator + "$" + v.name + `}"`
+}
+
+// profileFile returns the shell startup file holding the managed block:
+// $PATHED_PROFILE if set, otherwise ~/.zshrc, ~/.bashrc or ~/.profile depending on $SHELL
+func profileFile() (string, error) {
+	if file := os.Getenv("PATHED_PROFILE"); file != "" {
+		return file, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate home directory: %w", err)
+	}
+	switch filepath.Base(os.Getenv("SHELL")) {
+	case "zsh":
+		return filepath.Join(home, ".zshrc"), nil
+	case "bash":
+		return filepath.Join(home, ".bashrc"), nil
+	default:
+		return filepath.Join(home, ".profile"), nil
+	}
+}
+
+// profileHome stands for a leading ~ in the managed block: the shell doesn't expand ~
+// inside double quotes, but does expand ${HOME}
+const profileHome = "${HOME}"
+
+// quoteProfilePath escapes a PATH entry for use inside a double-quoted shell string.
+// '$' is left alone so references like $HOME are expanded by the shell, and a leading
+// ~ is written as ${HOME}.
+func quoteProfilePath(path string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`")
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		return profileHome + r.Replace(path[1:])
+	}
+	return r.Replace(path)
+}
+
+// unquoteProfilePath reverses quoteProfilePath
+func unquoteProfilePath(s string) string {
+	if rest, ok := strings.CutPrefix(s, profileHome); ok && (rest == "" || rest[0] == '/') {
+		s = "~" + rest
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\\\"`$", s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// formatProfileBlock renders the managed block that prepends the user entries to v
+func formatProfileBlock(userPaths []string, v pathVar) string {
+	quoted := make([]string, len(userPaths))
+	for i, p := range userPaths {
+		quoted[i] = quoteProfilePath(p)
+	}
+	blockStart, blockEnd := profileMarkers(v)
+	return blockStart + "\n" +
+		profileBlockNote + "\n" +
+		`export ` + v.name + `="` + v.join(quoted) + profileTail(v) + "\n" +
+		blockEnd + "\n"
+}
+
+// findProfileBlock returns the byte range [start, end) of v's managed block in content
+// (including the trailing newline), or -1, -1 if there is none
+func findProfileBlock(content string, v pathVar) (start, end int) {
+	blockStart, blockEnd := profileMarkers(v)
+	start = strings.Index(content, blockStart+"\n")
+	if start < 0 {
+		return -1, -1
+	}
+	rel := strings.Index(content[start:], blockEnd)
+	if rel < 0 {
+		return -1, -1
+	}
+	end = start + rel + len(blockEnd)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+	return start, end
+}
+
+// parseProfileBlock extracts the user entries from v's managed block in content
+func parseProfileBlock(content string, v pathVar) []string {
+	start, end := findProfileBlock(content, v)
+	if start < 0 {
+		return nil
+	}
+	prefix := `export ` + v.name + `="`
+	var paths []string
+	for _, line := range strings.Split(content[start:end], "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, prefix) || !strings.HasSuffix(line, profileTail(v)) {
+			continue
+		}
+		value := strings.TrimSuffix(strings.TrimPrefix(line, prefix), profileTail(v))
+		for _, p := range strings.Split(value, v.separator) {
+			if p != "" || v.keepEmpty {
+				paths = append(paths, unquoteProfilePath(p))
+			}
+		}
+	}
+	return paths
+}
+
+// replaceProfileBlock returns content with v's managed block replaced by block.
+// An empty block removes the managed block; a missing one is appended.
+func replaceProfileBlock(content, block string, v pathVar) string {
+	start, end := findProfileBlock(content, v)
+	if start >= 0 {
+		before := content[:start]
+		if block == "" {
+			// Drop the blank line that separated the block from the rest of the file
+			before = strings.TrimSuffix(before, "\n\n") + "\n"
+			if strings.TrimSpace(before) == "" {
+				before = ""
+			}
+		}
+		return before + block + content[end:]
+	}
+	if block == "" {
+		return content
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if content != "" {
+		content += "\n"
+	}
+	return content + block
+}
+
+// loadPathsFromProfile reads user entries of v from its managed block of the shell
+// profile and treats the rest of the process value as inherited system entries.
+// A missing profile has no user entries yet.
+func loadPathsFromProfile(v pathVar) ([]pathEntry, error) {
+	file, err := profileFile()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return withInheritedPaths(parseProfileBlock(string(content), v), v), nil
+}
+
+// saveProfilePaths writes the user entries to v's managed block of the shell profile.
+// Only writes the file if the block has actually changed.
+func saveProfilePaths(paths []pathEntry, v pathVar) error {
+	file, err := profileFile()
+	if err != nil {
+		return err
+	}
+
+	var block string
+	if user := sectionPaths(paths, "user"); len(user) > 0 {
+		block = formatProfileBlock(user, v)
+	}
+	return updateFile(file, func(content string) string {
+		return replaceProfileBlock(content, block, v)
+	})
+}
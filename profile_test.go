@@ -0,0 +1,179 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestProfileLoad(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", "/usr/bin")
+	b := profileBackend{v: pathVariable}
+
+	// A missing profile has no user entries yet
+	t.Setenv("PATHED_PROFILE", filepath.Join(dir, "missing"))
+	paths, err := b.Load()
+	if err != nil {
+		t.Fatalf("Load() of a missing profile = %v, want nil", err)
+	}
+	if got, want := entryList(paths), []string{"system:/usr/bin"}; !slices.Equal(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+
+	file := filepath.Join(dir, "profile")
+	content := "alias ll='ls -l'\n\n" + formatProfileBlock([]string{"/u1"}, pathVariable)
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATHED_PROFILE", file)
+	paths, err = b.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := entryList(paths), []string{"user:/u1", "system:/usr/bin"}; !slices.Equal(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+
+	// An unreadable profile is an error, not an empty user section
+	t.Setenv("PATHED_PROFILE", dir)
+	if _, err := b.Load(); err == nil {
+		t.Error("Load() of a directory = nil, want an error")
+	}
+}
+
+// profileRoundTrip are entries with characters the managed block has to escape or keep
+var profileRoundTrip = []string{
+	"~",
+	"~/bin",
+	"~user/bin",
+	"$HOME/go/bin",
+	"${XDG_DATA_HOME}/bin",
+	`/with "quotes"`,
+	`/back\slash\`,
+	`/esc\$aped`,
+	"/tick`date`",
+	"/with space",
+	"/it's",
+	"/~/mid",
+}
+
+func TestQuoteProfilePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"~", "${HOME}"},
+		{"~/bin", "${HOME}/bin"},
+		{"~user/bin", "~user/bin"},
+		{"$HOME/bin", "$HOME/bin"},
+		{`/a"b`, `/a\"b`},
+		{`/a\b`, `/a\\b`},
+		{"/a`b`", "/a\\`b\\`"},
+	}
+	for _, tt := range tests {
+		if got := quoteProfilePath(tt.path); got != tt.want {
+			t.Errorf("quoteProfilePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+	for _, p := range profileRoundTrip {
+		if got := unquoteProfilePath(quoteProfilePath(p)); got != p {
+			t.Errorf("unquoteProfilePath(quoteProfilePath(%q)) = %q", p, got)
+		}
+	}
+	// ${HOME} written by hand loads as the ~ it is written for
+	if got := unquoteProfilePath("${HOME}/bin"); got != "~/bin" {
+		t.Errorf("unquoteProfilePath(${HOME}/bin) = %q, want ~/bin", got)
+	}
+	if got := unquoteProfilePath("${HOMEDIR}/bin"); got != "${HOMEDIR}/bin" {
+		t.Errorf("unquoteProfilePath(${HOMEDIR}/bin) = %q, want it unchanged", got)
+	}
+}
+
+func TestProfileBlockRoundTrip(t *testing.T) {
+	manpath := lookupVar("MANPATH")
+	block := formatProfileBlock(profileRoundTrip, pathVariable)
+	if got := parseProfileBlock(block, pathVariable); !slices.Equal(got, profileRoundTrip) {
+		t.Errorf("parseProfileBlock(formatProfileBlock()) = %q, want %q", got, profileRoundTrip)
+	}
+	// Empty segments are kept only where they are meaningful
+	withEmpty := []string{"/a", "", "/b"}
+	if got := parseProfileBlock(formatProfileBlock(withEmpty, manpath), manpath); !slices.Equal(got, withEmpty) {
+		t.Errorf("MANPATH entries = %q, want %q", got, withEmpty)
+	}
+	// Each variable has its own block
+	if got := parseProfileBlock(block, manpath); got != nil {
+		t.Errorf("MANPATH entries of the PATH block = %q, want none", got)
+	}
+}
+
+func TestReplaceProfileBlock(t *testing.T) {
+	before := "# ~/.bashrc\nexport EDITOR=vi\n"
+	after := "alias ll='ls -l'\n"
+	old := formatProfileBlock([]string{"/old"}, pathVariable)
+	manBlock := formatProfileBlock([]string{"/man"}, lookupVar("MANPATH"))
+	block := formatProfileBlock([]string{"/new"}, pathVariable)
+	tests := []struct {
+		name    string
+		content string
+		block   string
+		want    string
+	}{
+		{"append to an empty file", "", block, block},
+		{"append after a blank line", before, block, before + "\n" + block},
+		{"append to a file without a final newline", "export EDITOR=vi", block, "export EDITOR=vi\n\n" + block},
+		{"replace in place", before + "\n" + old + after, block, before + "\n" + block + after},
+		{"keep other variables' blocks", before + "\n" + manBlock + "\n" + old, block, before + "\n" + manBlock + "\n" + block},
+		{"remove with its blank line", before + "\n" + old + after, "", before + after},
+		{"remove the only content", old, "", ""},
+		{"remove a missing block", before, "", before},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceProfileBlock(tt.content, tt.block, pathVariable); got != tt.want {
+				t.Errorf("replaceProfileBlock() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileSaveLoadRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "profile")
+	t.Setenv("PATHED_PROFILE", file)
+	t.Setenv("PATH", "/usr/bin")
+	before, after := "# my settings\nexport EDITOR=vi\n", "\n# more settings\nalias ll='ls -l'\n"
+	content := before + "\n" + formatProfileBlock([]string{"/old"}, pathVariable) + after
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	b := profileBackend{v: pathVariable}
+	paths, err := b.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths[0].deleted = true // /old
+	for _, p := range profileRoundTrip {
+		paths = insertPathEntry(paths, pathEntry{path: p, source: "user", added: true})
+	}
+	if err := b.Save(paths); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err = b.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sectionPaths(paths, "user"); !slices.Equal(got, profileRoundTrip) {
+		t.Errorf("user entries = %q, want %q", got, profileRoundTrip)
+	}
+	data, _ := os.ReadFile(file)
+	want := before + "\n" + formatProfileBlock(profileRoundTrip, pathVariable) + after
+	if string(data) != want {
+		t.Errorf("profile =\n%s\nwant\n%s", data, want)
+	}
+	if info, _ := os.Stat(file); info.Mode().Perm() != 0o600 {
+		t.Errorf("profile mode = %v, want 0600 kept", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// regValue is a registry Path value with its type: REG_EXPAND_SZ values have their
+// %VAR% references expanded by Windows when the environment is built, REG_SZ values don't
+type regValue struct {
+	data   string
+	expand bool // true for REG_EXPAND_SZ
+}
+
+// registryStore reads and writes values of the "system" (HKLM) and "user" (HKCU)
+// environment keys. A missing value reads as the zero regValue.
+type registryStore interface {
+	name() string
+	get(section, name string) (regValue, error)
+	set(section, name string, v regValue) error
+	// changed is called after values have been written
+	changed()
+}
+
+// registryBackend stores a variable in the system and user registry values
+type registryBackend struct {
+	store registryStore
+	v     pathVar
+}
+
+func (b registryBackend) Describe() string { return b.store.name() }
+func (b registryBackend) Var() pathVar     { return b.v }
+func (registryBackend) Sections() []string { return []string{"system", "user"} }
+
+// valueName returns the registry value holding the variable: Windows names PATH "Path"
+func (b registryBackend) valueName() string {
+	if b.v.isPath() {
+		return "Path"
+	}
+	return b.v.name
+}
+
+func (b registryBackend) Load() ([]pathEntry, error) {
+	var entries []pathEntry
+	for _, section := range b.Sections() {
+		value, err := b.store.get(section, b.valueName())
+		if err != nil {
+			return nil, fmt.Errorf("reading %s %s: %w", section, b.v.name, err)
+		}
+		for _, p := range b.v.split(value.data) {
+			if p != "" || b.v.keepEmpty {
+				entries = append(entries, pathEntry{path: p, source: section, exists: b.v.exists(p)})
+			}
+		}
+	}
+	return entries, nil
+}
+
+// Save writes each section's value if it has changed. The value type is kept:
+// REG_EXPAND_SZ stays REG_EXPAND_SZ, and a REG_SZ value that gains a %VAR% reference
+// is upgraded so the reference keeps working.
+//
+// An entry moved between sections changes both values, so the save is all or nothing:
+// sections gaining entries are written first, so a moved entry is never missing from
+// both, and if a write fails the values already written are restored.
+func (b registryBackend) Save(paths []pathEntry) error {
+	type write struct {
+		section        string
+		current, value regValue
+	}
+	var writes []write
+	for _, section := range b.Sections() {
+		current, err := b.store.get(section, b.valueName())
+		if err != nil {
+			return fmt.Errorf("reading %s %s: %w", section, b.v.name, err)
+		}
+		data := b.v.join(sectionPaths(paths, section))
+		value := regValue{data: data, expand: current.expand || strings.Contains(data, "%")}
+		if value != current {
+			writes = append(writes, write{section, current, value})
+		}
+	}
+	sort.SliceStable(writes, func(i, j int) bool {
+		return b.gainsEntries(writes[i].current, writes[i].value) && !b.gainsEntries(writes[j].current, writes[j].value)
+	})
+
+	for n, w := range writes {
+		if err := b.store.set(w.section, b.valueName(), w.value); err != nil {
+			for _, done := range slices.Backward(writes[:n]) {
+				if rerr := b.store.set(done.section, b.valueName(), done.current); rerr != nil {
+					return fmt.Errorf("%w (restoring %s %s also failed: %v)", err, done.section, b.v.name, rerr)
+				}
+			}
+			return err
+		}
+	}
+	if len(writes) > 0 {
+		b.store.changed()
+	}
+	return nil
+}
+
+// gainsEntries returns true if value holds an entry that current doesn't
+func (b registryBackend) gainsEntries(current, value regValue) bool {
+	old := b.v.split(current.data)
+	for _, p := range b.v.split(value.data) {
+		if !slices.Contains(old, p) {
+			return true
+		}
+	}
+	return false
+}
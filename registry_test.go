@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// memRegistry is an in-memory registryStore. It lets registry behaviour (system/user
+// split, value types, access-denied errors) be exercised on any platform. Read-only
+// sections reject writes like HKLM without elevation.
+type memRegistry struct {
+	values   map[regKey]regValue
+	readOnly map[string]bool // section -> rejects writes
+}
+
+// regKey identifies a value in memRegistry
+type regKey struct {
+	section, name string
+}
+
+func (r *memRegistry) name() string { return "fake registry (in memory)" }
+func (r *memRegistry) changed()     {}
+
+func (r *memRegistry) get(section, name string) (regValue, error) {
+	return r.values[regKey{section, name}], nil
+}
+
+func (r *memRegistry) set(section, name string, v regValue) error {
+	if r.readOnly[section] {
+		return fmt.Errorf("access denied: run as Administrator to modify %s %s", section, name)
+	}
+	r.values[regKey{section, name}] = v
+	return nil
+}
+
+// useTestRegistry makes registry mode use store for the rest of the test
+func useTestRegistry(t *testing.T, store registryStore) {
+	t.Helper()
+	testRegistry = store
+	t.Cleanup(func() { testRegistry = nil })
+}
+
+// newTestRegistry returns a registry backend for PATH over an in-memory store holding
+// the given system and user entries, both as REG_EXPAND_SZ
+func newTestRegistry(system, user []string) (registryBackend, *memRegistry) {
+	store := &memRegistry{
+		values: map[regKey]regValue{
+			{"system", "Path"}: {data: pathVariable.join(system), expand: true},
+			{"user", "Path"}:   {data: pathVariable.join(user), expand: true},
+		},
+		readOnly: map[string]bool{},
+	}
+	return registryBackend{store: store, v: pathVariable}, store
+}
+
+// storedPaths returns the entries of a section of the in-memory registry
+func storedPaths(store *memRegistry, section string) []string {
+	return pathVariable.split(store.values[regKey{section, "Path"}].data)
+}
+
+func TestRegistryLoad(t *testing.T) {
+	b, _ := newTestRegistry([]string{"/s1", "/s2"}, []string{"/u1"})
+	paths, err := b.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pathEntry{
+		{path: "/s1", source: "system"},
+		{path: "/s2", source: "system"},
+		{path: "/u1", source: "user"},
+	}
+	if len(paths) != len(want) {
+		t.Fatalf("Load() = %v, want %v", paths, want)
+	}
+	for i, p := range paths {
+		if p.path != want[i].path || p.source != want[i].source {
+			t.Errorf("entry %d = %s %s, want %s %s", i, p.source, p.path, want[i].source, want[i].path)
+		}
+	}
+}
+
+func TestRegistrySave(t *testing.T) {
+	b, store := newTestRegistry([]string{"/s1", "/s2"}, []string{"/u1"})
+	paths, _ := b.Load()
+	paths[0].deleted = true
+	paths = insertPathEntry(paths, pathEntry{path: "/u2", source: "user", added: true})
+	if err := b.Save(paths); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := storedPaths(store, "system"), []string{"/s2"}; !slices.Equal(got, want) {
+		t.Errorf("system = %q, want %q", got, want)
+	}
+	if got, want := storedPaths(store, "user"), []string{"/u1", "/u2"}; !slices.Equal(got, want) {
+		t.Errorf("user = %q, want %q", got, want)
+	}
+}
+
+func TestRegistrySaveSkipsUnchangedSections(t *testing.T) {
+	b, store := newTestRegistry([]string{"/s1"}, []string{"/u1"})
+	store.readOnly["system"] = true // like HKLM without elevation
+	paths, _ := b.Load()
+	paths = insertPathEntry(paths, pathEntry{path: "/u2", source: "user", added: true})
+	if err := b.Save(paths); err != nil {
+		t.Fatalf("Save() with only user changes = %v, want nil", err)
+	}
+	if got, want := storedPaths(store, "user"), []string{"/u1", "/u2"}; !slices.Equal(got, want) {
+		t.Errorf("user = %q, want %q", got, want)
+	}
+}
+
+func TestRegistrySavePreservesExpandType(t *testing.T) {
+	b, store := newTestRegistry([]string{`%SystemRoot%\system32`, "/s1"}, []string{"/u1"})
+	store.values[regKey{"user", "Path"}] = regValue{data: "/u1"} // REG_SZ
+	paths, _ := b.Load()
+	if paths[0].path != `%SystemRoot%\system32` {
+		t.Fatalf("Load() expanded the reference: %q", paths[0].path)
+	}
+
+	// Changing a REG_EXPAND_SZ value keeps its type and its references
+	paths[1].deleted = true
+	// Changing a REG_SZ value without references keeps it REG_SZ
+	paths = insertPathEntry(paths, pathEntry{path: "/u2", source: "user", added: true})
+	if err := b.Save(paths); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := store.values[regKey{"system", "Path"}], (regValue{data: `%SystemRoot%\system32`, expand: true}); got != want {
+		t.Errorf("system = %+v, want %+v", got, want)
+	}
+	if got, want := store.values[regKey{"user", "Path"}], (regValue{data: pathVariable.join([]string{"/u1", "/u2"})}); got != want {
+		t.Errorf("user = %+v, want %+v", got, want)
+	}
+
+	// A REG_SZ value gaining a reference becomes REG_EXPAND_SZ, so the reference works
+	paths, _ = b.Load()
+	paths = insertPathEntry(paths, pathEntry{path: `%USERPROFILE%\bin`, source: "user", added: true})
+	if err := b.Save(paths); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.values[regKey{"user", "Path"}]; !got.expand {
+		t.Errorf("user = %+v, want REG_EXPAND_SZ", got)
+	}
+}
+
+// recordingRegistry is a memRegistry that records which sections are written
+type recordingRegistry struct {
+	*memRegistry
+	writes []string
+}
+
+func (r *recordingRegistry) set(section, name string, v regValue) error {
+	r.writes = append(r.writes, section)
+	return r.memRegistry.set(section, name, v)
+}
+
+func TestRegistrySaveRollsBackOnPartialFailure(t *testing.T) {
+	b, store := newTestRegistry([]string{"/s1", "/s2"}, []string{"/u1"})
+	store.readOnly["system"] = true // like HKLM without elevation
+	before := maps.Clone(store.values)
+
+	// Moving /s2 to the user section: the user value gains it and is written first,
+	// then the system value fails and the user value is restored
+	rec := &recordingRegistry{memRegistry: store}
+	b.store = rec
+	paths, _ := b.Load()
+	paths[1].source = "user"
+	paths = movePathEntry(paths, 1, 2)
+	if err := b.Save(paths); err == nil {
+		t.Fatal("Save() = nil, want the system write to fail")
+	}
+	if want := []string{"user", "system", "user"}; !slices.Equal(rec.writes, want) {
+		t.Errorf("writes = %q, want %q", rec.writes, want)
+	}
+	if !maps.Equal(store.values, before) {
+		t.Errorf("values after failed save = %+v, want %+v", store.values, before)
+	}
+
+	// Moving /u1 to the system section: the system value gains it and fails first,
+	// so the user value is never written
+	rec.writes = nil
+	paths, _ = b.Load()
+	paths[2].source = "system"
+	if err := b.Save(paths); err == nil {
+		t.Fatal("Save() = nil, want the system write to fail")
+	}
+	if want := []string{"system"}; !slices.Equal(rec.writes, want) {
+		t.Errorf("writes = %q, want %q", rec.writes, want)
+	}
+	if !maps.Equal(store.values, before) {
+		t.Errorf("values after failed save = %+v, want %+v", store.values, before)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// searchMatches returns, for each rune of path, whether it is part of a
+// case-insensitive match of query. Returns nil if there is no match.
+func searchMatches(path, query string) []bool {
+	if query == "" {
+		return nil
+	}
+	p := []rune(path)
+	q := []rune(query)
+	var marks []bool
+	for i := 0; i+len(q) <= len(p); i++ {
+		match := true
+		for j, r := range q {
+			if unicode.ToLower(p[i+j]) != unicode.ToLower(r) {
+				match = false
+				break
+			}
+		}
+		if match {
+			if marks == nil {
+				marks = make([]bool, len(p))
+			}
+			for j := range q {
+				marks[i+j] = true
+			}
+		}
+	}
+	return marks
+}
+
+// matchesSearch reports whether path contains query (case-insensitive)
+func matchesSearch(path, query string) bool {
+	return query == "" || searchMatches(path, query) != nil
+}
+
+// rows returns the indices into m.paths of the entries shown in the main list:
+// every entry, or only the entries matching the search query while filtering
+func (m model) rows() []int {
+	rows := make([]int, 0, len(m.paths))
+	for i, p := range m.paths {
+		if !m.filtered || matchesSearch(p.path, m.searchQuery) {
+			rows = append(rows, i)
+		}
+	}
+	return rows
+}
+
+// cursorIndex returns the index into m.paths of the entry under the cursor, or -1 if the list is empty
+func (m model) cursorIndex() int {
+	rows := m.rows()
+	if m.list.cursor < 0 || m.list.cursor >= len(rows) {
+		return -1
+	}
+	return rows[m.list.cursor]
+}
+
+// rowOf returns the row showing the entry at index idx, or -1 if it is hidden by the filter
+func (m model) rowOf(idx int) int {
+	for row, i := range m.rows() {
+		if i == idx {
+			return row
+		}
+	}
+	return -1
+}
+
+// clampCursor keeps the cursor within the visible rows after the row count changed
+func (m *model) clampCursor() {
+	m.list.cursor = min(m.list.cursor, max(0, len(m.rows())-1))
+	m.list.SetViewHeight(m.list.TotalHeight(), len(m.rows()))
+}
+
+// jumpToMatch moves the cursor to the next (or previous) row matching the search query,
+// wrapping around. If includeCurrent is true, the row under the cursor counts as a match.
+func (m *model) jumpToMatch(forward, includeCurrent bool) {
+	rows := m.rows()
+	if m.searchQuery == "" || len(rows) == 0 {
+		return
+	}
+	for n := 0; n < len(rows); n++ {
+		step := n
+		if !includeCurrent {
+			step++
+		}
+		if !forward {
+			step = -step
+		}
+		row := ((m.list.cursor+step)%len(rows) + len(rows)) % len(rows)
+		if matchesSearch(m.paths[rows[row]].path, m.searchQuery) {
+			m.list.cursor = row
+			m.list.EnsureVisible()
+			return
+		}
+	}
+}
+
+// updateSearch handles input while typing a search query
+func (m model) updateSearch(key string, runes []rune) model {
+	switch key {
+	case keyEnter:
+		m.searching = false
+		return m
+	case keyEsc:
+		// Cancel search: clear query and filter, return to where the search started
+		m.searching = false
+		m.searchQuery = ""
+		m.filtered = false
+		m.list.cursor = max(0, m.rowOf(m.searchOrigin))
+		m.clampCursor()
+		return m
+	case "backspace":
+		if m.searchQuery != "" {
+			_, size := utf8.DecodeLastRuneInString(m.searchQuery)
+			m.searchQuery = m.searchQuery[:len(m.searchQuery)-size]
+		}
+	default:
+		if len(runes) == 0 {
+			return m
+		}
+		m.searchQuery += string(runes)
+	}
+	// Incremental search: jump to the first match at or after the starting entry
+	m.list.cursor = max(0, m.rowOf(m.searchOrigin))
+	m.clampCursor()
+	m.jumpToMatch(true, true)
+	return m
+}
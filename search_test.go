@@ -0,0 +1,112 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSearchMatches(t *testing.T) {
+	tests := []struct {
+		path, query string
+		want        string // x marks a matched rune
+	}{
+		{"/usr/bin", "", ""},
+		{"/usr/bin", "bin", "     xxx"},
+		{"/usr/BIN", "bin", "     xxx"},
+		{"/bin/bin", "bin", " xxx xxx"},
+		{"/usr/bin", "sbin", ""},
+		{"/café/bin", "É", "    x    "},
+		{"aaa", "aa", "xxx"},
+	}
+	for _, tt := range tests {
+		var got string
+		if marks := searchMatches(tt.path, tt.query); marks != nil {
+			for _, m := range marks {
+				if m {
+					got += "x"
+				} else {
+					got += " "
+				}
+			}
+		}
+		if got != tt.want {
+			t.Errorf("searchMatches(%q, %q) = %q, want %q", tt.path, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFilteredRows(t *testing.T) {
+	m := newTestModel(t, nil, []string{"/usr/bin", "/opt/go/bin", "/home/u/go/bin", "/sbin"})
+	if got, want := m.rows(), []int{0, 1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("rows() = %v, want %v", got, want)
+	}
+
+	m.searchQuery, m.filtered = "GO", true
+	if got, want := m.rows(), []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("filtered rows() = %v, want %v", got, want)
+	}
+	m.list.cursor = 1
+	if got := m.cursorIndex(); got != 2 {
+		t.Errorf("cursorIndex() = %d, want 2", got)
+	}
+	if got := m.rowOf(2); got != 1 {
+		t.Errorf("rowOf(2) = %d, want 1", got)
+	}
+	if got := m.rowOf(0); got != -1 {
+		t.Errorf("rowOf(hidden entry) = %d, want -1", got)
+	}
+
+	// The cursor stays within the rows when the filter shrinks them
+	m.searchQuery = "/home"
+	m.clampCursor()
+	if got := m.cursorIndex(); got != 2 {
+		t.Errorf("cursorIndex() after narrowing = %d, want 2", got)
+	}
+	m.searchQuery = "nothing"
+	m.clampCursor()
+	if got := m.cursorIndex(); got != -1 {
+		t.Errorf("cursorIndex() without rows = %d, want -1", got)
+	}
+}
+
+func TestJumpToMatch(t *testing.T) {
+	m := newTestModel(t, nil, []string{"/a/bin", "/b", "/c/bin", "/d"})
+	m.searchQuery = "bin"
+	m.list.cursor = 0
+	m.jumpToMatch(true, false)
+	if m.list.cursor != 2 {
+		t.Errorf("next match = %d, want 2", m.list.cursor)
+	}
+	m.jumpToMatch(true, false)
+	if m.list.cursor != 0 {
+		t.Errorf("next match wrapping = %d, want 0", m.list.cursor)
+	}
+	m.jumpToMatch(false, false)
+	if m.list.cursor != 2 {
+		t.Errorf("previous match wrapping = %d, want 2", m.list.cursor)
+	}
+	m.jumpToMatch(true, true)
+	if m.list.cursor != 2 {
+		t.Errorf("match including the current row = %d, want 2", m.list.cursor)
+	}
+}
+
+func TestIncrementalSearch(t *testing.T) {
+	m := newTestModel(t, nil, []string{"/a", "/opt/go", "/b", "/go"})
+	m.list.cursor = 2
+	m.searching, m.searchOrigin = true, 2
+	m = m.updateSearch("g", []rune("g"))
+	if m.list.cursor != 3 {
+		t.Errorf("cursor after typing = %d, want 3 (the first match from where the search started)", m.list.cursor)
+	}
+	m = m.updateSearch("backspace", nil)
+	m = m.updateSearch("/", []rune("/"))
+	m = m.updateSearch("o", []rune("o"))
+	if m.searchQuery != "/o" || m.list.cursor != 1 {
+		t.Errorf("query = %q, cursor = %d, want \"/o\", 1", m.searchQuery, m.list.cursor)
+	}
+	m = m.updateSearch(keyEsc, nil)
+	if m.searching || m.searchQuery != "" || m.list.cursor != 2 {
+		t.Errorf("after Esc: searching = %v, query = %q, cursor = %d, want false, \"\", 2", m.searching, m.searchQuery, m.list.cursor)
+	}
+}
@@ -0,0 +1,362 @@
+package main
+
+import "slices"
+
+// Multi-select: Space selects the entry under the cursor, v starts a range that
+// follows the cursor until v is pressed again. Del, J/K, T/B, m, dd, yy and + act on
+// every selected entry, or on the cursor entry when nothing is selected, leaving out
+// inherited entries the store doesn't save. The mark is kept on the entries, so it
+// follows them when they move and undo restores it.
+
+// visualRange returns the rows between the visual mode anchor and the cursor,
+// or an empty range (lo > hi) outside visual mode
+func (m model) visualRange() (lo, hi int) {
+	if !m.visual {
+		return 0, -1
+	}
+	anchor := m.rowOf(m.visualAnchor)
+	if anchor < 0 {
+		anchor = m.list.cursor // anchor entry hidden by the filter
+	}
+	return min(anchor, m.list.cursor), max(anchor, m.list.cursor)
+}
+
+// selectedRows returns the selected rows, including the visual range and the entries
+// moving to the other section while a position is picked, in list order
+func (m model) selectedRows() []int {
+	lo, hi := m.visualRange()
+	var out []int
+	for row, idx := range m.rows() {
+		if m.paths[idx].selected || (row >= lo && row <= hi) || (m.pick != nil && m.pick.selected[idx]) {
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+// hasSelection returns true if visual mode is on or a visible entry is selected
+func (m model) hasSelection() bool {
+	return len(m.selectedRows()) > 0
+}
+
+// toggleVisual starts a range selection at the cursor entry, or ends it, keeping
+// the range selected
+func (m *model) toggleVisual() {
+	if m.visual {
+		m.materializeSelection()
+		return
+	}
+	if idx := m.cursorIndex(); idx >= 0 {
+		m.visual = true
+		m.visualAnchor = idx
+	}
+}
+
+// toggleSelected selects or deselects the cursor entry and moves to the next row
+func (m *model) toggleSelected() {
+	m.materializeSelection()
+	if idx := m.cursorIndex(); idx >= 0 {
+		m.paths[idx].selected = !m.paths[idx].selected
+		m.list.MoveDown(len(m.rows()))
+	}
+}
+
+// materializeSelection ends visual mode, marking the rows of its range as selected
+func (m *model) materializeSelection() {
+	lo, hi := m.visualRange()
+	rows := m.rows()
+	for row := lo; row <= hi; row++ {
+		m.paths[rows[row]].selected = true
+	}
+	m.visual = false
+}
+
+// clearSelection ends visual mode and deselects every entry
+func (m *model) clearSelection() {
+	for i := range m.paths {
+		m.paths[i].selected = false
+	}
+	m.visual = false
+}
+
+// selection ends visual mode and returns which entries the bulk operations act on,
+// indexed like m.paths: the visible selected entries, or the cursor entry
+func (m *model) selection() []bool {
+	rows := m.rows()
+	selected := make([]bool, len(m.paths))
+	selectedRows := m.selectedRows()
+	if len(selectedRows) == 0 && m.list.cursor < len(rows) {
+		selectedRows = []int{m.list.cursor}
+	}
+	for _, row := range selectedRows {
+		selected[rows[row]] = true
+	}
+	m.materializeSelection()
+	return selected
+}
+
+// editableSelection is like selection, leaving out inherited entries, which the store
+// doesn't save
+func (m *model) editableSelection() []bool {
+	selected := m.selection()
+	for i := range selected {
+		selected[i] = selected[i] && !inherited(m.backend, m.paths[i])
+	}
+	return selected
+}
+
+// toggleDeleteSelection marks the selected entries for deletion, or clears the mark
+// if all of them have it
+func (m *model) toggleDeleteSelection() {
+	selected := m.editableSelection()
+	if !slices.Contains(selected, true) {
+		return
+	}
+	deleted := false
+	for i, sel := range selected {
+		if sel && !m.paths[i].deleted {
+			deleted = true
+		}
+	}
+	m.checkpoint()
+	for i, sel := range selected {
+		if sel {
+			m.paths[i].deleted = deleted
+		}
+	}
+}
+
+// moveSelection moves each block of selected entries up (or down) past the next
+// visible entry. Entries stay within their section: a block at the edge of its
+// section stays where it is.
+func (m *model) moveSelection(up bool) {
+	selected := m.editableSelection()
+	rows := m.rows()
+	sel := make([]bool, len(rows))
+	for row, idx := range rows {
+		sel[row] = selected[idx]
+	}
+	step := 1
+	if up {
+		step = -1
+	}
+	moved := false
+	for n := range sel {
+		// Going up, the top of a block moves first, so the rest follow into its place
+		from := n
+		if !up {
+			from = len(sel) - 1 - n
+		}
+		to := from + step
+		if to < 0 || to >= len(sel) || !sel[from] || sel[to] {
+			continue
+		}
+		rows = m.rows()
+		if m.paths[rows[from]].source != m.paths[rows[to]].source {
+			continue
+		}
+		if !moved {
+			m.checkpoint()
+			moved = true
+		}
+		m.paths = movePathEntry(m.paths, rows[from], rows[to])
+		sel[from], sel[to] = false, true
+		if m.list.cursor == from {
+			m.list.cursor = to
+		} else if m.list.cursor == to {
+			m.list.cursor = from
+		}
+	}
+	m.list.EnsureVisible()
+}
+
+// moveSelectionToEdge moves the selected entries to the top (or bottom) of their
+// sections, keeping their order
+func (m *model) moveSelectionToEdge(top bool) {
+	selected := m.editableSelection()
+	order := make([]int, 0, len(m.paths))
+	for start := 0; start < len(m.paths); {
+		end := start
+		for end < len(m.paths) && m.paths[end].source == m.paths[start].source {
+			end++
+		}
+		var picked, rest []int
+		for i := start; i < end; i++ {
+			if selected[i] {
+				picked = append(picked, i)
+			} else {
+				rest = append(rest, i)
+			}
+		}
+		if top {
+			order = append(append(order, picked...), rest...)
+		} else {
+			order = append(append(order, rest...), picked...)
+		}
+		start = end
+	}
+	if slices.IsSorted(order) {
+		return // already there
+	}
+	m.checkpoint()
+	for i, idx := range order {
+		if selected[idx] && i != idx {
+			m.paths[idx].modified = true
+		}
+	}
+	m.applyOrder(order)
+}
+
+// sectionTarget returns the section the selected entries (or the cursor entry) would
+// move to: "system", "user", or "" if the selection spans both
+func (m model) sectionTarget() string {
+	rows := m.rows()
+	selectedRows := m.selectedRows()
+	if len(selectedRows) == 0 && m.list.cursor < len(rows) {
+		selectedRows = []int{m.list.cursor}
+	}
+	target := ""
+	for n, row := range selectedRows {
+		other := "system"
+		if m.paths[rows[row]].source == "system" {
+			other = "user"
+		}
+		if n > 0 && other != target {
+			return ""
+		}
+		target = other
+	}
+	return target
+}
+
+// changeSectionOfSelection moves the selected entries to the other section of a store
+// with system and user sections, at its top (or bottom), keeping their order. See
+// startSectionPick for placing them next to a chosen entry instead.
+func (m *model) changeSectionOfSelection(top bool) {
+	selected := m.selection()
+	var system, toSystem, toUser, user []int
+	for i, p := range m.paths {
+		switch {
+		case selected[i] && p.source == "user":
+			toSystem = append(toSystem, i)
+		case selected[i] && p.source == "system":
+			toUser = append(toUser, i)
+		case p.source == "system":
+			system = append(system, i)
+		default:
+			user = append(user, i)
+		}
+	}
+	if len(toSystem) == 0 && len(toUser) == 0 {
+		return
+	}
+	m.checkpoint()
+	for _, i := range toSystem {
+		m.paths[i].source = "system"
+		m.paths[i].modified = true
+	}
+	for _, i := range toUser {
+		m.paths[i].source = "user"
+		m.paths[i].modified = true
+	}
+	if top {
+		m.applyOrder(slices.Concat(toSystem, system, toUser, user))
+	} else {
+		m.applyOrder(slices.Concat(system, toSystem, user, toUser))
+	}
+}
+
+// pickState holds the entries being moved to the other section while the user
+// picks the entry to place them next to
+type pickState struct {
+	selected []bool // entries being moved, indexed like m.paths
+	target   string // section they move to
+}
+
+// startSectionPick records the entries the bulk operations act on as moving to the
+// target section and puts the cursor on the first entry of that section. The entries
+// themselves are left as they are until the move, so it is undone as one edit.
+func (m *model) startSectionPick(target string) {
+	m.pick = &pickState{selected: m.selection(), target: target}
+	m.visual = false // the moving entries are fixed, while the cursor moves
+	start, _ := sectionBounds(m.paths, target)
+	if row := m.rowOf(start); row >= 0 {
+		m.list.cursor = row
+		m.list.EnsureVisible()
+	}
+}
+
+// updateSectionPick handles keys while the position of entries moved to the other
+// section is picked: Enter or P places them above the cursor entry, p below it
+func (m model) updateSectionPick(key string) model {
+	rows := m.rows()
+	switch key {
+	case keyUp, keyUpAlt:
+		m.list.MoveUp()
+	case keyDown, keyDownAlt:
+		m.list.MoveDown(len(rows))
+	case keyPgUp, keyPgUpAlt:
+		m.list.PageUp()
+	case keyPgDown, keyPgDownAlt:
+		m.list.PageDown(len(rows))
+	case keyHome, keyHomeAlt:
+		m.list.Home()
+	case keyEnd, keyEndAlt:
+		m.list.End(len(rows))
+	case keyEnter, keyPasteAbove, keyPasteBelow:
+		idx := m.cursorIndex()
+		if idx < 0 || m.paths[idx].source != m.pick.target {
+			break // the position must be an entry of the target section
+		}
+		m.changeSectionAt(m.pick.selected, idx, key == keyPasteBelow)
+		m.pick = nil
+	case keyEsc:
+		m.pick = nil
+	}
+	return m
+}
+
+// changeSectionAt moves the entries in selected to the section of the entry at idx,
+// above it (or below it), keeping their order
+func (m *model) changeSectionAt(selected []bool, idx int, below bool) {
+	target := m.paths[idx].source
+	var moving, rest []int
+	for i, p := range m.paths {
+		if selected[i] && p.source != target {
+			moving = append(moving, i)
+		} else {
+			rest = append(rest, i)
+		}
+	}
+	if len(moving) == 0 {
+		return
+	}
+	at := slices.Index(rest, idx)
+	if below {
+		at++
+	}
+	m.checkpoint()
+	for _, i := range moving {
+		m.paths[i].source = target
+		m.paths[i].modified = true
+	}
+	m.applyOrder(slices.Concat(rest[:at], moving, rest[at:]))
+}
+
+// applyOrder rearranges the entries so that entry order[i] ends up at index i,
+// keeping the cursor on its entry
+func (m *model) applyOrder(order []int) {
+	cur, newCur := m.cursorIndex(), -1
+	paths := make([]pathEntry, len(order))
+	for i, idx := range order {
+		paths[i] = m.paths[idx]
+		if idx == cur {
+			newCur = i
+		}
+	}
+	m.paths = paths
+	if row := m.rowOf(newCur); row >= 0 {
+		m.list.cursor = row
+		m.list.EnsureVisible()
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// newTestModel returns an editor over a test registry with the given entries
+func newTestModel(t *testing.T, system, user []string) model {
+	t.Helper()
+	b, _ := newTestRegistry(system, user)
+	m, err := initialModel(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// entryList renders entries as "source:path", in order, for comparison
+func entryList(paths []pathEntry) []string {
+	var out []string
+	for _, p := range paths {
+		out = append(out, p.source+":"+p.path)
+	}
+	return out
+}
+
+func TestChangeSectionOfSelection(t *testing.T) {
+	tests := []struct {
+		name     string
+		selected []int
+		top      bool
+		want     []string
+	}{
+		{
+			name:     "user entries to the top of system",
+			selected: []int{2, 3},
+			top:      true,
+			want:     []string{"system:/u1", "system:/u2", "system:/s1", "system:/s2", "user:/u3"},
+		},
+		{
+			name:     "system entry to the bottom of user",
+			selected: []int{0},
+			want:     []string{"system:/s2", "user:/u1", "user:/u2", "user:/u3", "user:/s1"},
+		},
+		{
+			name:     "both ways at once",
+			selected: []int{1, 2},
+			top:      true,
+			want:     []string{"system:/u1", "system:/s1", "user:/s2", "user:/u2", "user:/u3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, []string{"/s1", "/s2"}, []string{"/u1", "/u2", "/u3"})
+			for _, i := range tt.selected {
+				m.paths[i].selected = true
+			}
+			m.changeSectionOfSelection(tt.top)
+			if got := entryList(m.paths); !slices.Equal(got, tt.want) {
+				t.Errorf("entries = %q, want %q", got, tt.want)
+			}
+			m.undo()
+			if got, want := entryList(m.paths), []string{"system:/s1", "system:/s2", "user:/u1", "user:/u2", "user:/u3"}; !slices.Equal(got, want) {
+				t.Errorf("entries after undo = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestChangeSectionOfSelectionMarksModified(t *testing.T) {
+	m := newTestModel(t, []string{"/s1"}, []string{"/u1", "/u2"})
+	m.paths[2].selected = true
+	m.changeSectionOfSelection(false)
+	for _, p := range m.paths {
+		if want := p.path == "/u2"; p.modified != want {
+			t.Errorf("%s modified = %v, want %v", p.path, p.modified, want)
+		}
+	}
+}
+
+func TestSectionPick(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		want []string
+	}{
+		{
+			name: "above the picked entry",
+			keys: []string{keyDown, keyEnter},
+			want: []string{"system:/s1", "system:/u2", "system:/u3", "system:/s2", "user:/u1"},
+		},
+		{
+			name: "below the picked entry",
+			keys: []string{keyDown, keyPasteBelow},
+			want: []string{"system:/s1", "system:/s2", "system:/u2", "system:/u3", "user:/u1"},
+		},
+		{
+			name: "not on an entry of the target section",
+			keys: []string{keyEnd, keyEnter, keyEsc},
+			want: []string{"system:/s1", "system:/s2", "user:/u1", "user:/u2", "user:/u3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, []string{"/s1", "/s2"}, []string{"/u1", "/u2", "/u3"})
+			m.paths[3].selected = true
+			m.paths[4].selected = true
+			m.startSectionPick("system")
+			for _, key := range tt.keys {
+				m = m.updateSectionPick(key)
+			}
+			if m.pick != nil {
+				t.Fatal("still picking a position")
+			}
+			if got := entryList(m.paths); !slices.Equal(got, tt.want) {
+				t.Errorf("entries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSectionPickCursorEntry(t *testing.T) {
+	// Without a selection the cursor entry moves, and it isn't left selected
+	m := newTestModel(t, []string{"/s1", "/s2"}, []string{"/u1"})
+	m.list.cursor = 2
+	m.startSectionPick("system")
+	m = m.updateSectionPick(keyPasteBelow)
+	if got, want := entryList(m.paths), []string{"system:/s1", "system:/u1", "system:/s2"}; !slices.Equal(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+	if m.hasSelection() {
+		t.Error("entries left selected after the move")
+	}
+
+	// Undo restores the entries as they were before m, without a selection mark
+	m.undo()
+	if got, want := entryList(m.paths), []string{"system:/s1", "system:/s2", "user:/u1"}; !slices.Equal(got, want) {
+		t.Errorf("entries after undo = %q, want %q", got, want)
+	}
+	if m.hasSelection() {
+		t.Error("entries selected after undo")
+	}
+}
+
+func TestSectionPickCancel(t *testing.T) {
+	m := newTestModel(t, []string{"/s1"}, []string{"/u1", "/u2"})
+	m.list.cursor = 1
+	m.startSectionPick("system")
+	if got, want := m.selectedRows(), []int{1}; !slices.Equal(got, want) {
+		t.Errorf("rows shown as moving = %v, want %v", got, want)
+	}
+	m = m.updateSectionPick(keyEsc)
+	if m.pick != nil || m.hasSelection() || m.hasModifications() || len(m.history.undo) != 0 {
+		t.Error("cancelled pick left changes behind")
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// executable is a command found in a PATH directory
+type executable struct {
+	name string // command name, as typed (without PATHEXT extension on Windows)
+	file string // file name in the directory
+}
+
+// scanExecutables returns the executables in a directory, sorted by command name.
+// If several files provide the same command (python.exe and python.bat on Windows),
+// only the one the shell would pick is returned.
+func scanExecutables(dir string) []executable {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	index := make(map[string]int) // command -> position in execs
+	var execs []executable
+	var ranks []int
+	for _, entry := range entries {
+		info, err := os.Stat(filepath.Join(dir, entry.Name())) // follows symlinks
+		if err != nil {
+			continue
+		}
+		name, rank, ok := commandName(entry.Name(), info)
+		if !ok {
+			continue
+		}
+		if i, seen := index[name]; seen {
+			if rank < ranks[i] {
+				execs[i].file, ranks[i] = entry.Name(), rank
+			}
+			continue
+		}
+		index[name] = len(execs)
+		execs = append(execs, executable{name: name, file: entry.Name()})
+		ranks = append(ranks, rank)
+	}
+	slices.SortFunc(execs, func(a, b executable) int { return strings.Compare(a.name, b.name) })
+	return execs
+}
+
+// execCache caches directory scans by expanded, normalized directory, so the
+// analysis can be recomputed cheaply after every edit
+type execCache map[string][]executable
+
+// scan returns the executables of a PATH entry's directory
+func (c execCache) scan(path string) []executable {
+	dir := expandPath(path)
+	key := normalizePath(dir)
+	execs, ok := c[key]
+	if !ok {
+		execs = scanExecutables(dir)
+		c[key] = execs
+	}
+	return execs
+}
+
+// drop forgets the executables of a PATH entry's directory, so the next scan reads
+// it again
+func (c execCache) drop(path string) {
+	delete(c, normalizePath(expandPath(path)))
+}
+
+// shadowAnalysis records, for every command on PATH, which entries provide it
+type shadowAnalysis struct {
+	execs     [][]executable   // per entry; nil for deleted entries and repeated directories
+	providers map[string][]int // command -> indices of entries providing it, in PATH order
+}
+
+// shadowConflict is a command shared between an entry and other entries
+type shadowConflict struct {
+	command string
+	entries []int // the entries hidden by the entry, or the one entry that hides it
+}
+
+// analyzeShadows scans every non-deleted entry for executables and records which
+// entries provide each command. A directory that appears more than once is only
+// counted at its first position, like the shell does.
+func analyzeShadows(paths []pathEntry, cache execCache) *shadowAnalysis {
+	a := &shadowAnalysis{
+		execs:     make([][]executable, len(paths)),
+		providers: make(map[string][]int),
+	}
+	seen := make(map[string]bool)
+	for i, p := range paths {
+		if p.deleted {
+			continue
+		}
+		key := normalizePath(expandPath(p.path))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		a.execs[i] = cache.scan(p.path)
+		for _, e := range a.execs[i] {
+			a.providers[e.name] = append(a.providers[e.name], i)
+		}
+	}
+	return a
+}
+
+// shadows returns the commands entry i provides that hide the same command in later entries
+func (a *shadowAnalysis) shadows(i int) []shadowConflict {
+	var conflicts []shadowConflict
+	for _, e := range a.execs[i] {
+		if providers := a.providers[e.name]; providers[0] == i && len(providers) > 1 {
+			conflicts = append(conflicts, shadowConflict{command: e.name, entries: providers[1:]})
+		}
+	}
+	return conflicts
+}
+
+// shadowedBy returns the commands entry i provides that an earlier entry hides
+func (a *shadowAnalysis) shadowedBy(i int) []shadowConflict {
+	var conflicts []shadowConflict
+	for _, e := range a.execs[i] {
+		if providers := a.providers[e.name]; providers[0] != i {
+			conflicts = append(conflicts, shadowConflict{command: e.name, entries: providers[:1]})
+		}
+	}
+	return conflicts
+}
+
+// whichMatch is an executable a command resolves to
+type whichMatch struct {
+	index int    // entry providing the executable
+	file  string // file name in the entry's directory
+}
+
+// which returns every executable the command resolves to, in PATH order;
+// the first one wins
+func (a *shadowAnalysis) which(command string) []whichMatch {
+	name := commandKey(command)
+	var matches []whichMatch
+	for _, idx := range a.providers[name] {
+		for _, e := range a.execs[idx] {
+			if e.name == name {
+				matches = append(matches, whichMatch{index: idx, file: e.file})
+				break
+			}
+		}
+	}
+	return matches
+}
@@ -0,0 +1,65 @@
+package main
+
+// undoLimit caps the number of undo levels kept
+const undoLimit = 100
+
+// snapshot captures the editable state of the main list for undo/redo
+type snapshot struct {
+	paths  []pathEntry
+	cursor int
+}
+
+// history holds the undo and redo stacks for the main list
+type history struct {
+	undo []snapshot
+	redo []snapshot
+}
+
+// takeSnapshot copies the current paths and cursor position
+func (m *model) takeSnapshot() snapshot {
+	return snapshot{
+		paths:  append([]pathEntry(nil), m.paths...),
+		cursor: m.list.cursor,
+	}
+}
+
+// restoreSnapshot replaces the current paths and cursor position with a snapshot
+func (m *model) restoreSnapshot(s snapshot) {
+	m.paths = s.paths
+	m.list.cursor = s.cursor
+	m.visual = false // the range anchor may no longer exist
+	m.clampCursor()
+}
+
+// checkpoint records the current state before an edit. Any redo history is discarded.
+func (m *model) checkpoint() {
+	m.history.undo = append(m.history.undo, m.takeSnapshot())
+	if len(m.history.undo) > undoLimit {
+		m.history.undo = m.history.undo[len(m.history.undo)-undoLimit:]
+	}
+	m.history.redo = nil
+}
+
+// undo restores the state before the last edit. Returns false if there is nothing to undo.
+func (m *model) undo() bool {
+	if len(m.history.undo) == 0 {
+		return false
+	}
+	last := m.history.undo[len(m.history.undo)-1]
+	m.history.undo = m.history.undo[:len(m.history.undo)-1]
+	m.history.redo = append(m.history.redo, m.takeSnapshot())
+	m.restoreSnapshot(last)
+	return true
+}
+
+// redo reapplies the last undone edit. Returns false if there is nothing to redo.
+func (m *model) redo() bool {
+	if len(m.history.redo) == 0 {
+		return false
+	}
+	last := m.history.redo[len(m.history.redo)-1]
+	m.history.redo = m.history.redo[:len(m.history.redo)-1]
+	m.history.undo = append(m.history.undo, m.takeSnapshot())
+	m.restoreSnapshot(last)
+	return true
+}
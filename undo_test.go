@@ -0,0 +1,85 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestUndoRedo(t *testing.T) {
+	m := newTestModel(t, nil, []string{"/a", "/b", "/c"})
+	if m.undo() || m.redo() {
+		t.Fatal("undo or redo with an empty history")
+	}
+
+	m.list.cursor = 1
+	m.checkpoint()
+	m.paths[1].deleted = true
+	m.list.cursor = 2
+	m.checkpoint()
+	m.paths = movePathEntry(m.paths, 2, 0)
+
+	if !m.undo() {
+		t.Fatal("undo() = false")
+	}
+	if got, want := entryList(m.paths), []string{"user:/a", "user:/b", "user:/c"}; !slices.Equal(got, want) || !m.paths[1].deleted {
+		t.Errorf("after one undo: entries = %q, /b deleted = %v", got, m.paths[1].deleted)
+	}
+	if m.list.cursor != 2 {
+		t.Errorf("cursor = %d, want 2", m.list.cursor)
+	}
+	m.undo()
+	if m.paths[1].deleted || m.list.cursor != 1 {
+		t.Errorf("after two undos: /b deleted = %v, cursor = %d, want false, 1", m.paths[1].deleted, m.list.cursor)
+	}
+
+	// Redo reapplies in order
+	m.redo()
+	m.redo()
+	if got, want := entryList(m.paths), []string{"user:/c", "user:/a", "user:/b"}; !slices.Equal(got, want) {
+		t.Errorf("after redo: entries = %q, want %q", got, want)
+	}
+	if m.redo() {
+		t.Error("redo() past the last edit = true")
+	}
+
+	// A new edit discards what could be redone
+	m.undo()
+	m.checkpoint()
+	m.paths[0].deleted = true
+	if m.redo() {
+		t.Error("redo() after a new edit = true")
+	}
+}
+
+func TestUndoLimit(t *testing.T) {
+	m := newTestModel(t, nil, []string{"/a"})
+	for i := range undoLimit + 5 {
+		m.checkpoint()
+		m.paths[0].path = "/a" + string(rune('0'+i%10))
+	}
+	if len(m.history.undo) != undoLimit {
+		t.Fatalf("undo levels = %d, want %d", len(m.history.undo), undoLimit)
+	}
+	n := 0
+	for m.undo() {
+		n++
+	}
+	if n != undoLimit {
+		t.Errorf("undid %d edits, want %d", n, undoLimit)
+	}
+	// The oldest levels were dropped, so the original entry can't be reached
+	if m.paths[0].path == "/a" {
+		t.Error("undid past the limit")
+	}
+}
+
+func TestUndoEndsVisualMode(t *testing.T) {
+	m := newTestModel(t, nil, []string{"/a", "/b"})
+	m.checkpoint()
+	m.paths = m.paths[:1]
+	m.visual = true
+	m.undo()
+	if m.visual {
+		t.Error("visual mode still on after undo")
+	}
+}
@@ -8,7 +8,7 @@ import (
 
 // saveAndQuitMsg is sent when user chooses a save option from the quit prompt
 type saveAndQuitMsg struct {
-	saveType int // 0 = discard, 1 = output edited (env mode), 2 = persist to registry
+	saveType int // 0 = discard, 1 = output edited (env mode), 2 = persist (persistent stores)
 }
 
 func doSaveAndQuit(saveType int) tea.Cmd {
@@ -17,34 +17,89 @@ func doSaveAndQuit(saveType int) tea.Cmd {
 	}
 }
 
+// switchVarMsg is sent when the user confirms switching to another variable
+type switchVarMsg struct {
+	v pathVar
+}
+
+// showDiffMsg is sent when the user chooses to view the diff from the quit prompt
+type showDiffMsg struct{}
+
+// changeSectionMsg is sent when the user chooses where entries moved to the other
+// section go: its top or bottom, or a position picked in the list
+type changeSectionMsg struct {
+	top    bool
+	pick   bool
+	target string
+}
+
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.viewWidth = msg.Width
-		// Subtract lines for: help bar (1) + warning if not elevated in registry mode (1)
+		// Subtract lines for: help bar (1) + mode warning (1)
 		reservedLines := 1
-		if m.registryMode && !m.elevated {
+		if m.modeWarning() != "" {
 			reservedLines = 2
 		}
 		height := msg.Height - reservedLines
 		if height < 1 {
 			height = 1
 		}
-		m.list.SetViewHeight(height, len(m.paths))
+		m.height = height
+		m.resizeList()
 		// Also update browser's or help view's list state if active
 		if m.browser != nil {
 			m.browser.list.SetViewHeight(height, len(m.browser.entries))
 		}
+		if m.editor != nil {
+			m.editor.height = height
+		}
 		if m.helpView != nil {
 			m.helpView.list.SetViewHeight(height, len(m.helpView.lines))
 		}
+		if m.varPicker != nil {
+			m.varPicker.list.SetViewHeight(height, len(m.varPicker.names))
+		}
+		if m.diffView != nil {
+			m.diffView.list.SetViewHeight(height, len(m.diffView.rows))
+		}
+		if m.mergeView != nil {
+			m.mergeView.height = height
+			m.mergeView.resize()
+		}
+		return m, nil
+
+	case switchVarMsg:
+		return m.switchVar(msg.v), nil
+
+	case showDiffMsg:
+		m.diffView = newDiffView(m, true)
+		return m, nil
+
+	case changeSectionMsg:
+		if msg.pick {
+			m.startSectionPick(msg.target)
+		} else {
+			m.changeSectionOfSelection(msg.top)
+		}
 		return m, nil
 
 	case saveAndQuitMsg:
 		switch msg.saveType {
 		case 1: // Env mode: output edited PATH
 			m.saveChanges = true
-		case 2: // Registry mode: persist to registry (handled in main.go after TUI exits)
+		case 2: // Persistent store: persist (handled in main.go after TUI exits)
+			// unless another program changed the store: merge its changes first
+			external, err := m.externalChange()
+			if err != nil {
+				m.prompt = newPrompt("Error reading "+m.backend.Describe()+": "+err.Error(), []string{"OK"}, nil)
+				return m, nil
+			}
+			if external != nil {
+				m.mergeView = newMergeView(m, external)
+				return m, nil
+			}
 			m.saveChanges = true
 		default: // Discard changes
 			m.saveChanges = false
@@ -52,12 +107,33 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 
 	case tea.KeyMsg:
+		if m.searching {
+			return m.updateSearch(msg.String(), msg.Runes), nil
+		}
+		if m.typingWhich {
+			return m.updateWhichInput(msg.String(), msg.Runes), nil
+		}
+		if m.pick != nil {
+			return m.updateSectionPick(msg.String()), nil
+		}
 		if m.helpView != nil {
 			return m.updateHelpView(msg)
 		}
+		if m.varPicker != nil {
+			return m.updateVarPicker(msg)
+		}
+		if m.mergeView != nil {
+			return m.updateMergeView(msg)
+		}
+		if m.diffView != nil {
+			return m.updateDiffView(msg)
+		}
 		if m.browser != nil {
 			return m.updateBrowser(msg)
 		}
+		if m.editor != nil {
+			return m.updateEditor(msg)
+		}
 		if m.prompt != nil {
 			return m.updatePrompt(msg)
 		}
@@ -78,49 +154,190 @@ func (m model) updateHelpView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+func (m model) updateDiffView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	returnToQuit := m.diffView.returnToQuit
+	m.diffView = m.diffView.Update(msg)
+	if m.diffView == nil && returnToQuit {
+		m.prompt = m.quitPrompt()
+	}
+	return m, nil
+}
+
+func (m model) updateMergeView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	external := m.mergeView.external
+	var merged []pathEntry
+	var ok bool
+	m.mergeView, merged, ok = m.mergeView.Update(msg)
+	if !ok {
+		return m, nil
+	}
+	// The external value is the new starting point: saving now writes exactly the
+	// differences shown by the diff view
+	m.checkpoint()
+	m.paths = merged
+	m.base = external
+	m.fingerprint = fingerprint(m.backend, external)
+	m.originalPath = buildPathString(external, m.backend.Var())
+	m.clampCursor()
+	m.prompt = m.quitPrompt()
+	return m, nil
+}
+
+func (m model) updateVarPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var name string
+	m.varPicker, name = m.varPicker.Update(msg)
+	if name == "" || name == m.backend.Var().name {
+		return m, nil
+	}
+	v := lookupVar(name)
+	if !m.hasModifications() {
+		return m.switchVar(v), nil
+	}
+	// Switching reloads the entries, so pending changes would be lost
+	m.prompt = newPrompt("Discard changes to "+m.backend.Var().name+" and edit "+v.name+"?", []string{"Switch and discard", "Cancel"}, func(index int) tea.Cmd {
+		if index == 0 {
+			return func() tea.Msg { return switchVarMsg{v: v} }
+		}
+		return nil
+	})
+	return m, nil
+}
+
+// switchVar reloads the editor with variable v from the same kind of store,
+// keeping the window size. Errors are shown in a prompt and leave m unchanged.
+func (m model) switchVar(v pathVar) model {
+	b, err := withVar(m.backend, v)
+	if err != nil {
+		m.prompt = newPrompt(err.Error(), []string{"OK"}, nil)
+		return m
+	}
+	next, err := initialModel(b)
+	if err != nil {
+		m.prompt = newPrompt("Error loading "+v.name+": "+err.Error(), []string{"OK"}, nil)
+		return m
+	}
+	next.viewWidth, next.height = m.viewWidth, m.height
+	next.register, next.clipboard = m.register, m.clipboard
+	next.resizeList()
+	return next
+}
+
 func (m model) updateBrowser(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	newBrowser, cmd, selectedPath := m.browser.Update(msg)
 	if newBrowser == nil {
 		// Browser closed
-		if selectedPath != "" {
-			if m.browser.editingIndex == -1 {
-				// Add mode - create new path entry
-				newEntry := pathEntry{
-					path:     selectedPath,
-					source:   m.browser.addSource,
-					modified: true,
-					deleted:  false,
-					added:    true,
-					exists:   dirExists(selectedPath),
-				}
-				// Insert at appropriate position based on source
-				m.paths = insertPathEntry(m.paths, newEntry)
-				// Move cursor to the new entry
-				for i, p := range m.paths {
-					if p.path == selectedPath && p.source == newEntry.source {
-						m.list.cursor = i
-						break
-					}
-				}
-			} else {
-				// Edit mode - update the existing path entry
-				idx := m.browser.editingIndex
-				if m.paths[idx].path != selectedPath {
-					m.paths[idx].path = selectedPath
-					m.paths[idx].modified = true
-					m.paths[idx].deleted = false // clear deletion mark when editing
-					m.paths[idx].exists = dirExists(selectedPath)
-				}
-			}
+		if m.browser.typePath {
+			// Switch to typing the path, starting from the browsed directory
+			m.editor = newLineEditor(selectedPath, m.browser.editingIndex, m.browser.addSource, m.height)
+		} else if selectedPath != "" {
+			m.applySelectedPath(m.browser.editingIndex, m.browser.addSource, selectedPath)
 		}
 		m.browser = nil
+		m.clampCursor()
 	} else {
 		m.browser = newBrowser
 	}
 	return m, cmd
 }
 
+func (m model) updateEditor(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	newEditor, cmd, selectedPath := m.editor.Update(msg)
+	if newEditor == nil {
+		// Editor closed
+		if selectedPath != "" {
+			m.applySelectedPath(m.editor.editingIndex, m.editor.addSource, selectedPath)
+		}
+		m.editor = nil
+		m.clampCursor()
+	} else {
+		m.editor = newEditor
+	}
+	return m, cmd
+}
+
+// applySelectedPath adds a new entry (editingIndex == -1) or updates an existing one
+// with a path chosen in the browser or typed in the line editor
+func (m *model) applySelectedPath(editingIndex int, addSource, selectedPath string) {
+	if editingIndex == -1 {
+		m.materializeSelection() // the insert shifts the range anchor
+		m.checkpoint()
+		// Add mode - create new path entry
+		newEntry := pathEntry{
+			path:     selectedPath,
+			source:   addSource,
+			modified: true,
+			deleted:  false,
+			added:    true,
+			exists:   m.backend.Var().exists(selectedPath),
+		}
+		// Insert at appropriate position based on source
+		m.paths = insertPathEntry(m.paths, newEntry)
+		// Move cursor to the new entry (if it is not hidden by the filter)
+		for i, p := range m.paths {
+			if p.path == selectedPath && p.source == newEntry.source {
+				if row := m.rowOf(i); row >= 0 {
+					m.list.cursor = row
+				}
+				break
+			}
+		}
+	} else {
+		// Edit mode - update the existing path entry
+		idx := editingIndex
+		if m.paths[idx].path != selectedPath {
+			m.checkpoint()
+			m.paths[idx].path = selectedPath
+			m.paths[idx].modified = true
+			m.paths[idx].deleted = false // clear deletion mark when editing
+			m.paths[idx].exists = m.backend.Var().exists(selectedPath)
+		}
+	}
+}
+
+// quitPrompt asks what to do with the changes when quitting
+func (m model) quitPrompt() *prompt {
+	viewDiff := func() tea.Msg { return showDiffMsg{} }
+	if persists(m.backend) {
+		// Persistent store: persist or discard
+		return newPrompt("Persist changes to "+m.backend.Describe()+"?", []string{"Persist", "Don't persist", "View diff"}, func(index int) tea.Cmd {
+			switch index {
+			case 0:
+				return doSaveAndQuit(2) // persist
+			case 1:
+				return doSaveAndQuit(0) // discard
+			}
+			return viewDiff
+		})
+	}
+	// Env mode: output edited or original value
+	return newPrompt("Output edited "+m.backend.Var().name+"?", []string{"Edited", "Original", "View diff"}, func(index int) tea.Cmd {
+		switch index {
+		case 0:
+			return doSaveAndQuit(1) // output edited
+		case 1:
+			return doSaveAndQuit(0) // output original
+		}
+		return viewDiff
+	})
+}
+
 func (m model) updateMain(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rows := m.rows()
+	if msg.Paste {
+		// Text pasted from the terminal: add the paths it holds below the cursor
+		m.pasteEntries(pastedPaths(string(msg.Runes), m.backend.Var()), true)
+		return m, nil
+	}
+	// Two-key commands: the second key must repeat the first
+	pending := m.pendingKey
+	m.pendingKey = ""
+	switch {
+	case pending == keyCut && msg.String() == keyCut:
+		return m, m.cutSelection()
+	case pending == keyYank && msg.String() == keyYank:
+		return m, m.yankSelection()
+	}
+
 	switch msg.String() {
 	case keyForceQuit:
 		return m, tea.Quit
@@ -131,29 +348,13 @@ func (m model) updateMain(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		}
 		// Changes exist, ask what to do
-		if m.registryMode {
-			// Registry mode: persist to registry or discard
-			m.prompt = newPrompt("Persist changes to registry?", []string{"Persist", "Don't persist"}, func(index int) tea.Cmd {
-				if index == 0 {
-					return doSaveAndQuit(2) // persist to registry
-				}
-				return doSaveAndQuit(0) // discard
-			})
-		} else {
-			// Env mode: output edited or original PATH
-			m.prompt = newPrompt("Output edited PATH?", []string{"Edited", "Original"}, func(index int) tea.Cmd {
-				if index == 0 {
-					return doSaveAndQuit(1) // output edited
-				}
-				return doSaveAndQuit(0) // output original
-			})
-		}
+		m.prompt = m.quitPrompt()
 
 	case keyUp, keyUpAlt:
 		m.list.MoveUp()
 
 	case keyDown, keyDownAlt:
-		m.list.MoveDown(len(m.paths))
+		m.list.MoveDown(len(rows))
 
 	case keyLeft:
 		m.list.ScrollLeft()
@@ -162,7 +363,7 @@ func (m model) updateMain(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Find max path length to limit scrolling (in runes, not bytes)
 		maxLen := 0
 		for _, p := range m.paths {
-			runeLen := utf8.RuneCountInString(p.path)
+			runeLen := utf8.RuneCountInString(displayPath(p, m.showExpanded, m.backend.Var()))
 			if runeLen > maxLen {
 				maxLen = runeLen
 			}
@@ -170,7 +371,7 @@ func (m model) updateMain(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.list.ScrollRight(maxLen, m.viewWidth)
 
 	case keyPgDown, keyPgDownAlt:
-		m.list.PageDown(len(m.paths))
+		m.list.PageDown(len(rows))
 
 	case keyPgUp, keyPgUpAlt:
 		m.list.PageUp()
@@ -179,81 +380,214 @@ func (m model) updateMain(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.list.Home()
 
 	case keyEnd, keyEndAlt:
-		m.list.End(len(m.paths))
+		m.list.End(len(rows))
 
 	case keyMoveUp, keyMoveUpAlt:
-		// Move entry up (within section in registry mode, free in env mode)
-		canMove := m.list.cursor > 0
-		if m.registryMode {
-			canMove = canMove && m.paths[m.list.cursor-1].source == m.paths[m.list.cursor].source
-		}
-		if canMove {
-			m.paths[m.list.cursor], m.paths[m.list.cursor-1] = m.paths[m.list.cursor-1], m.paths[m.list.cursor]
-			m.list.MoveUp()
-			m.paths[m.list.cursor].modified = true // mark the moved entry (now at new position)
-		}
+		// Move the selected entries (or the cursor entry) up past the previous visible
+		// entry, within their section
+		m.moveSelection(true)
 
 	case keyMoveDn, keyMoveDnAlt:
-		// Move entry down (within section in registry mode, free in env mode)
-		canMove := m.list.cursor < len(m.paths)-1
-		if m.registryMode {
-			canMove = canMove && m.paths[m.list.cursor+1].source == m.paths[m.list.cursor].source
+		// Move the selected entries (or the cursor entry) down past the next visible
+		// entry, within their section
+		m.moveSelection(false)
+
+	case keyMoveTop:
+		m.moveSelectionToEdge(true)
+
+	case keyMoveBottom:
+		m.moveSelectionToEdge(false)
+
+	case keySection:
+		// Move the selected entries (or the cursor entry) between user and system,
+		// at the top or bottom of the other section, or next to an entry picked there
+		if !hasSection(m.backend, "system") || len(rows) == 0 {
+			break
 		}
-		if canMove {
-			m.paths[m.list.cursor], m.paths[m.list.cursor+1] = m.paths[m.list.cursor+1], m.paths[m.list.cursor]
-			m.list.MoveDown(len(m.paths))
-			m.paths[m.list.cursor].modified = true // mark the moved entry (now at new position)
+		m.materializeSelection()
+		target := m.sectionTarget()
+		question := "Move to " + target + " section at"
+		options := []string{"Top", "Bottom"}
+		if start, end := sectionBounds(m.paths, target); target == "" {
+			question = "Move to the other section at"
+		} else if start < end {
+			options = append(options, "Pick position")
 		}
+		m.prompt = newPrompt(question, options, func(index int) tea.Cmd {
+			return func() tea.Msg { return changeSectionMsg{top: index == 0, pick: index == 2, target: target} }
+		})
 
 	case keyDelete:
-		// Toggle deleted state on current entry
-		m.paths[m.list.cursor].deleted = !m.paths[m.list.cursor].deleted
+		// Toggle deleted state on the selected entries (or the cursor entry)
+		m.toggleDeleteSelection()
+
+	case keyMark:
+		m.toggleSelected()
+
+	case keyCut, keyYank:
+		m.pendingKey = msg.String()
+
+	case keyPasteBelow:
+		m.pasteEntries(m.register, true)
+
+	case keyPasteAbove:
+		m.pasteEntries(m.register, false)
+
+	case keyDuplicate:
+		m.duplicateSelection()
+
+	case keyVisual:
+		m.toggleVisual()
 
 	case keySelect:
-		// Open directory browser for the current entry
-		m.browser = newBrowser(m.paths[m.list.cursor].path, m.list.cursor, m.list.TotalHeight())
+		// Open directory browser for the current entry (inherited entries are read-only)
+		if idx := m.cursorIndex(); idx >= 0 && !inherited(m.backend, m.paths[idx]) {
+			m.browser = newBrowser(m.paths[idx].path, idx, m.height)
+		}
 
 	case keyAddUser:
-		// Add new PATH entry (user entry in registry mode, no source in env mode)
-		if m.registryMode {
-			m.browser = newBrowserForAdd("user", m.list.TotalHeight())
-		} else {
-			m.browser = newBrowserForAdd("", m.list.TotalHeight())
-		}
+		// Add new PATH entry (user entry in persistent stores, no source in env mode)
+		m.browser = newBrowserForAdd(defaultSource(m.backend), m.height)
 
 	case keyAddSystem:
-		// Add new system PATH entry (registry mode only)
-		if m.registryMode {
-			m.browser = newBrowserForAdd("system", m.list.TotalHeight())
+		// Add new system PATH entry (stores with a system section only)
+		if hasSection(m.backend, "system") {
+			m.browser = newBrowserForAdd("system", m.height)
+		}
+
+	case keyEditText:
+		// Type a new value for the current entry, starting from its current text
+		if idx := m.cursorIndex(); idx >= 0 && !inherited(m.backend, m.paths[idx]) {
+			m.editor = newLineEditor(m.paths[idx].path, idx, "", m.height)
+		}
+
+	case keyInsertUser:
+		// Type a new PATH entry (user entry in persistent stores, no source in env mode)
+		m.editor = newLineEditor("", -1, defaultSource(m.backend), m.height)
+
+	case keyInsertSys:
+		// Type a new system PATH entry (stores with a system section only)
+		if hasSection(m.backend, "system") {
+			m.editor = newLineEditor("", -1, "system", m.height)
 		}
 
 	case keyClean:
 		// Mark duplicates and non-existing paths for deletion
-		// In registry mode: duplicates within same source
+		// In persistent stores: duplicates within same source
 		// In env mode: duplicates globally
-		seen := make(map[string]map[string]bool) // source -> normalized path -> seen
-		seen["system"] = make(map[string]bool)
-		seen["user"] = make(map[string]bool)
-		seen[""] = make(map[string]bool) // for env mode
-
-		for i := range m.paths {
-			p := &m.paths[i]
-			// Check for non-existing path
-			if !p.exists {
-				p.deleted = true
-				p.modified = true
+		reasons := cleanReasons(m.backend, m.paths, true, true)
+		for i, reason := range reasons {
+			if reason != "" && !m.paths[i].deleted {
+				m.checkpoint() // one undo step for the whole clean
+				break
 			}
-			// Check for duplicate (within same source in registry mode, globally in env mode)
-			normalizedPath := normalizePath(p.path)
-			if seen[p.source][normalizedPath] {
-				p.deleted = true
-				p.modified = true
+		}
+		for i, reason := range reasons {
+			if reason != "" {
+				m.paths[i].deleted = true
+				m.paths[i].modified = true
 			}
-			seen[p.source][normalizedPath] = true
+		}
+
+	case keyUndo:
+		m.undo()
+
+	case keyRedo:
+		m.redo()
+
+	case keySearch:
+		// Start typing a new search query
+		m.searching = true
+		m.searchQuery = ""
+		m.searchOrigin = m.cursorIndex()
+
+	case keySearchNext:
+		m.jumpToMatch(true, false)
+
+	case keySearchPrev:
+		m.jumpToMatch(false, false)
+
+	case keyShadows:
+		// Toggle the pane showing which commands the cursor entry hides or is hidden by
+		m.togglePane(paneShadows)
+
+	case keyDetail:
+		// Toggle the detail pane for the entry under the cursor
+		m.togglePane(paneDetail)
+
+	case keyDetailUp, keyDetailDn, keyDetailPgUp, keyDetailPgDn:
+		// Scroll the detail pane
+		if m.pane == paneDetail {
+			switch msg.String() {
+			case keyDetailUp:
+				m.scrollDetail(func(l *listState, _ int) { l.ScrollUp() })
+			case keyDetailDn:
+				m.scrollDetail((*listState).ScrollDown)
+			case keyDetailPgUp:
+				m.scrollDetail(func(l *listState, _ int) { l.ScrollPageUp() })
+			case keyDetailPgDn:
+				m.scrollDetail((*listState).ScrollPageDown)
+			}
+		}
+
+	case keyWhich:
+		// Look up where a command resolves under the edited PATH (empty input closes the pane)
+		if !m.backend.Var().commands {
+			m.togglePane(paneWhich) // explains that only PATH holds commands
+			break
+		}
+		m.typingWhich = true
+		m.whichInput = m.whichCommand
+
+	case keyPickVar:
+		// Switch to another PATH-like variable in the same store. In default mode a
+		// bare value or lines are captured into one variable, so it is chosen with --var
+		// instead; the other formats name the variable they set.
+		if env, ok := m.backend.(envBackend); ok && !env.namesVar() {
+			m.prompt = newPrompt("The output is captured into one variable - run 'pathed --var NAME' to edit another, or use --format or 'pathed init' to switch here", []string{"OK"}, nil)
+			break
+		}
+		m.varPicker = newVarPicker(m.backend.Var().name, m.height)
+
+	case keyDiff:
+		// Show what saving (or outputting) the edited value would change
+		m.diffView = newDiffView(m, false)
+
+	case keyRescan:
+		// Directories are read once; pick up changes made on disk since
+		m.rescan()
+
+	case keyExpand:
+		// Toggle showing expanded paths next to entries with variable references
+		m.showExpanded = !m.showExpanded
+
+	case keyFilter:
+		// Toggle showing only entries that match the search query, keeping the cursor entry if possible
+		if m.searchQuery != "" || m.filtered {
+			idx := m.cursorIndex()
+			m.filtered = !m.filtered
+			if row := m.rowOf(idx); row >= 0 {
+				m.list.cursor = row
+			} else {
+				m.list.cursor = 0
+			}
+			m.clampCursor()
+		}
+
+	case keyEsc:
+		// Clear the selection, then search highlight and filter
+		if m.hasSelection() {
+			m.clearSelection()
+		} else if m.searchQuery != "" || m.filtered {
+			idx := m.cursorIndex()
+			m.searchQuery = ""
+			m.filtered = false
+			m.list.cursor = max(0, m.rowOf(idx))
+			m.clampCursor()
 		}
 
 	case keyHelp, keyHelpAlt:
-		m.helpView = newHelpView(m.list.TotalHeight())
+		m.helpView = newHelpView(m.height)
 	}
 	return m, nil
 }
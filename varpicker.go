@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// varPicker is a full-screen list of PATH-like variables to switch the editor to
+type varPicker struct {
+	names   []string
+	current string // variable being edited, marked in the list
+	list    listState
+}
+
+// newVarPicker creates a picker over varNames with the cursor on the current variable
+func newVarPicker(current string, height int) *varPicker {
+	p := &varPicker{names: varNames(), current: current}
+	p.list.SetViewHeight(height, len(p.names))
+	for i, name := range p.names {
+		if name == current {
+			p.list.cursor = i
+			p.list.EnsureVisible()
+		}
+	}
+	return p
+}
+
+// Update handles input for the picker.
+// Returns nil when closed, with the chosen variable name or "" if cancelled.
+func (p *varPicker) Update(msg tea.KeyMsg) (*varPicker, string) {
+	switch msg.String() {
+	case keyUp, keyUpAlt:
+		p.list.MoveUp()
+	case keyDown, keyDownAlt:
+		p.list.MoveDown(len(p.names))
+	case keyPgUp, keyPgUpAlt:
+		p.list.PageUp()
+	case keyPgDown, keyPgDownAlt:
+		p.list.PageDown(len(p.names))
+	case keyHome, keyHomeAlt:
+		p.list.Home()
+	case keyEnd, keyEndAlt:
+		p.list.End(len(p.names))
+	case keyEnter:
+		return nil, p.names[p.list.cursor]
+	case keyEsc, keyPickVar, keyQuit:
+		return nil, ""
+	}
+	return p, ""
+}
+
+// View renders the picker: one variable per line with its number of entries in the
+// environment
+func (p *varPicker) View(viewWidth int) string {
+	var sb strings.Builder
+	start, end := p.list.VisibleRange(len(p.names))
+	scrollbar := p.list.RenderScrollbar(len(p.names))
+
+	for i := start; i < end; i++ {
+		name := p.names[i]
+		cursor := "  "
+		if i == p.list.cursor {
+			cursor = " >"
+		}
+		note := "(not set)"
+		if value, ok := os.LookupEnv(name); ok {
+			n := len(lookupVar(name).split(value))
+			note = fmt.Sprintf("%d entries", n)
+			if n == 1 {
+				note = "1 entry"
+			}
+		}
+		if name == p.current {
+			note += ", editing"
+		}
+		text := truncateLine(fmt.Sprintf("%s %-24s %s", cursor, name, note), viewWidth-2)
+		line := text
+		if name == p.current {
+			line = ansiBold + text + ansiReset
+		}
+		if padding := viewWidth - 2 - utf8.RuneCountInString(text); padding > 0 {
+			line += strings.Repeat(" ", padding)
+		}
+		sb.WriteString(line + " " + scrollbar[i-start] + "\n")
+	}
+
+	// Pad remaining lines if content is shorter than viewport
+	for i := end - start; i < p.list.viewHeight; i++ {
+		scrollChar := " "
+		if i < len(scrollbar) {
+			scrollChar = scrollbar[i]
+		}
+		sb.WriteString(strings.Repeat(" ", viewWidth-1) + scrollChar + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPickVarDefaultMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		evalFile   string
+		evalFormat string
+		opens      bool
+	}{
+		{name: "bare value"},
+		{name: "lines", format: "lines"},
+		{name: "posix", format: "posix", opens: true},
+		{name: "json", format: "json", opens: true},
+		{name: "shell integration", evalFile: "/tmp/eval", evalFormat: "fish", opens: true},
+		{name: "explicit lines under the integration", format: "lines", evalFile: "/tmp/eval", evalFormat: "posix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(evalFileEnv, tt.evalFile)
+			t.Setenv(evalFormatEnv, tt.evalFormat)
+			m, err := initialModel(envBackend{v: pathVariable, format: tt.format})
+			if err != nil {
+				t.Fatal(err)
+			}
+			next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(keyPickVar)})
+			m = next.(model)
+			if opened := m.varPicker != nil; opened != tt.opens {
+				t.Errorf("picker opened = %v, want %v", opened, tt.opens)
+			}
+			if refused := m.prompt != nil; refused == tt.opens {
+				t.Errorf("prompt shown = %v, want %v", refused, !tt.opens)
+			}
+		})
+	}
+}
+
+func TestSwitchVarKeepsFormat(t *testing.T) {
+	m, err := initialModel(envBackend{v: pathVariable, format: "posix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = m.switchVar(lookupVar("MANPATH"))
+	env, ok := m.backend.(envBackend)
+	if !ok || env.v.name != "MANPATH" || env.format != "posix" {
+		t.Errorf("backend = %+v, want MANPATH in posix format", m.backend)
+	}
+}
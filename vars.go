@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+)
+
+// pathVar describes a list-style environment variable pathed can edit
+type pathVar struct {
+	name      string // environment variable name
+	separator string // separates entries in the value
+	// keepEmpty is true if an empty segment is meaningful and not a mistake, like
+	// MANPATH's, which stands for the default search path
+	keepEmpty bool
+	empty     string // what an empty segment means, shown in the list
+	files     bool   // entries may be files (archives) as well as directories
+	commands  bool   // entries hold commands, so shadow analysis and which apply
+}
+
+// pathListSeparator is the platform's separator for PATH-like variables
+const pathListSeparator = string(os.PathListSeparator)
+
+// emptyCurrentDir is the meaning of an empty segment in most variables
+const emptyCurrentDir = "current directory"
+
+// knownVars lists the PATH-like variables offered by the picker and completions
+var knownVars = []pathVar{
+	{name: "PATH", separator: pathListSeparator, empty: emptyCurrentDir, commands: true},
+	{name: "MANPATH", separator: ":", keepEmpty: true, empty: "default search path"},
+	{name: "INFOPATH", separator: ":", keepEmpty: true, empty: "default search path"},
+	{name: "LD_LIBRARY_PATH", separator: ":", empty: emptyCurrentDir},
+	{name: "DYLD_LIBRARY_PATH", separator: ":", empty: emptyCurrentDir},
+	{name: "LIBRARY_PATH", separator: pathListSeparator, empty: emptyCurrentDir},
+	{name: "CPATH", separator: pathListSeparator, empty: emptyCurrentDir},
+	{name: "PKG_CONFIG_PATH", separator: pathListSeparator, empty: emptyCurrentDir},
+	{name: "PYTHONPATH", separator: pathListSeparator, empty: emptyCurrentDir, files: true},
+	{name: "CLASSPATH", separator: pathListSeparator, empty: emptyCurrentDir, files: true},
+	{name: "NODE_PATH", separator: pathListSeparator, empty: emptyCurrentDir},
+	{name: "PERL5LIB", separator: pathListSeparator, empty: emptyCurrentDir},
+	{name: "GOPATH", separator: pathListSeparator, empty: emptyCurrentDir},
+	{name: "CDPATH", separator: ":", keepEmpty: true, empty: emptyCurrentDir},
+	{name: "XDG_DATA_DIRS", separator: ":", empty: "ignored"},
+	{name: "XDG_CONFIG_DIRS", separator: ":", empty: "ignored"},
+	{name: "PSModulePath", separator: pathListSeparator, empty: "ignored"},
+}
+
+// pathVariable is PATH, the variable edited unless --var selects another one
+var pathVariable = knownVars[0]
+
+// lookupVar returns the description of a variable. Unknown variables use the
+// platform's separator and the rules of PATH without the command analysis.
+// Names are case-insensitive on Windows, like environment variables there.
+func lookupVar(name string) pathVar {
+	for _, v := range knownVars {
+		if v.name == name || (os.PathListSeparator == ';' && strings.EqualFold(v.name, name)) {
+			return v
+		}
+	}
+	return pathVar{name: name, separator: pathListSeparator, empty: emptyCurrentDir}
+}
+
+// isPath returns true if the variable is PATH itself
+func (v pathVar) isPath() bool {
+	return v.name == pathVariable.name
+}
+
+// split returns the entries of a value. Empty segments are kept, so an unset or
+// empty variable has no entries but "a::b" has three.
+func (v pathVar) split(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, v.separator)
+}
+
+// join builds a value from entries
+func (v pathVar) join(entries []string) string {
+	return strings.Join(entries, v.separator)
+}
+
+// exists reports whether an entry refers to something on disk, after expanding
+// variable references. Meaningful empty segments always exist.
+func (v pathVar) exists(path string) bool {
+	if path == "" && v.keepEmpty {
+		return true
+	}
+	if v.files {
+		_, err := os.Stat(expandPath(path))
+		return path != "" && err == nil
+	}
+	return pathExists(path)
+}
+
+// varNames returns the names of the known variables followed by any other set
+// variable whose name ends in PATH or DIRS, sorted
+func varNames() []string {
+	var names []string
+	for _, v := range knownVars {
+		names = append(names, v.name)
+	}
+	var others []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		upper := strings.ToUpper(name)
+		if (strings.HasSuffix(upper, "PATH") || strings.HasSuffix(upper, "DIRS")) && !slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, name) }) {
+			others = append(others, name)
+		}
+	}
+	slices.Sort(others)
+	return append(names, others...)
+}
@@ -1,9 +1,41 @@
 package main
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
-// renderEntryPrefix returns the 2-character prefix for a path entry (state marker + cursor/exists marker)
-func renderEntryPrefix(entry pathEntry, isCursor bool) string {
+// expandedSeparator separates an entry's raw text from its expanded path in the list
+const expandedSeparator = "  => "
+
+// emptyEntryText is shown for an empty segment of v, with what it means
+func emptyEntryText(v pathVar) string {
+	return "(empty: " + v.empty + ")"
+}
+
+// displayPath returns an entry's text as shown in the list. If showExpanded is set,
+// entries with variable references show the expanded path alongside the raw text.
+func displayPath(entry pathEntry, showExpanded bool, v pathVar) string {
+	if entry.path == "" {
+		return emptyEntryText(v)
+	}
+	if expanded := expandPath(entry.path); showExpanded && expanded != entry.path {
+		return entry.path + expandedSeparator + expanded
+	}
+	return entry.path
+}
+
+// severityColor returns the color of the audit marker for a severity
+func severityColor(sev severity) string {
+	if sev == severityHigh {
+		return ansiRed
+	}
+	return ansiYellow
+}
+
+// renderEntryPrefix returns the 2-character prefix for a path entry (state marker + cursor/audit/exists marker).
+// sev is the entry's highest audit finding; a finding takes precedence over the missing marker.
+func renderEntryPrefix(entry pathEntry, isCursor bool, sev severity) string {
 	// First char: modification state (priority: deleted > added > modified)
 	var prefix string
 	if entry.deleted {
@@ -16,13 +48,17 @@ func renderEntryPrefix(entry pathEntry, isCursor bool) string {
 		prefix = " "
 	}
 
-	// Second char: cursor or exists indicator
+	// Second char: cursor, audit or exists indicator
 	if isCursor {
-		if !entry.exists {
+		if sev != severityNone {
+			prefix += severityColor(sev) + ">" + ansiReset
+		} else if !entry.exists {
 			prefix += ansiBlue + ">" + ansiReset
 		} else {
 			prefix += ">"
 		}
+	} else if sev != severityNone {
+		prefix += severityColor(sev) + "!" + ansiReset
 	} else if !entry.exists {
 		prefix += ansiBlue + "?" + ansiReset
 	} else {
@@ -47,21 +83,98 @@ func renderEntryStyle(entry pathEntry) (style string, needsReset bool) {
 	return "", false
 }
 
-// renderHelpBar returns the help bar text for the main view
-func renderHelpBar(registryMode bool, width int) string {
+// renderHighlighted returns runes with search matches shown in reverse video, or
+// in normal video if the text is already reversed (a selected entry)
+func renderHighlighted(runes []rune, marks []bool, reversed bool) string {
+	var b strings.Builder
+	highlighted := false
+	for i, r := range runes {
+		if marks[i] != highlighted {
+			highlighted = marks[i]
+			if highlighted != reversed {
+				b.WriteString(ansiReverse)
+			} else {
+				b.WriteString(ansiNoReverse)
+			}
+		}
+		b.WriteRune(r)
+	}
+	if highlighted && reversed {
+		b.WriteString(ansiReverse)
+	} else if highlighted {
+		b.WriteString(ansiNoReverse)
+	}
+	return b.String()
+}
+
+// renderSearchBar returns the bottom bar while a search is being typed or is active
+func renderSearchBar(query string, typing, filtered bool, matches, width int) string {
+	var bar string
+	if typing {
+		bar = " /" + query + "_"
+	} else {
+		bar = fmt.Sprintf(" Search: %s (%d matches) | n/N: next/prev | f: filter | Esc: clear", query, matches)
+		if filtered {
+			bar = fmt.Sprintf(" Filter: %s (%d matches) | n/N: next/prev | f: show all | Esc: clear", query, matches)
+		}
+	}
+	if len(bar) > width {
+		bar = bar[:width-3] + "..."
+	}
+	return bar
+}
+
+// modeWarning returns a warning line shown above the help bar, or "" if none applies
+func (m model) modeWarning() string {
+	switch {
+	case hasSection(m.backend, "system") && !m.elevated:
+		return " Warning: Not running as Administrator - system " + m.backend.Var().name + " changes will fail"
+	case persists(m.backend) && !hasSection(m.backend, "system"):
+		return " Note: only user entries are saved to " + m.backend.Describe() + " - inherited system entries are read-only"
+	}
+	return ""
+}
+
+// renderHelpBar returns the help bar text for the main view, naming the variable
+// being edited unless it is PATH
+func renderHelpBar(systemSection bool, v pathVar, width int) string {
 	var addHelp string
-	if registryMode {
+	if systemSection {
 		addHelp = "a/A: add user/system"
 	} else {
 		addHelp = "a: add"
 	}
-	helpBar := " Tab: edit | " + addHelp + " | c: clean | Del: delete | q: quit | ?: help"
+	helpBar := " Tab: browse | e: type | " + addHelp + " | c: clean | Del: delete | Space/v: select | /: search | q: quit | ?: help"
+	if !v.isPath() {
+		helpBar = " [" + v.name + "]" + helpBar
+	}
 	if len(helpBar) > width {
 		helpBar = helpBar[:width-3] + "..."
 	}
 	return helpBar
 }
 
+// renderSelectionBar returns the help bar shown while entries are selected
+func renderSelectionBar(count int, visual, systemSection bool, width int) string {
+	bar := fmt.Sprintf(" %d selected | Del: delete | J/K/T/B: move", count)
+	if visual {
+		bar = " VISUAL" + bar
+	}
+	if systemSection {
+		bar += " | m: user/system"
+	}
+	return truncateLine(bar+" | Esc: clear", width)
+}
+
+// truncateLine shortens a line to width runes, marking the cut with "..."
+func truncateLine(line string, width int) string {
+	runes := []rune(line)
+	if len(runes) > width {
+		return string(runes[:max(0, width-3)]) + "..."
+	}
+	return line
+}
+
 func (m model) View() string {
 	var b strings.Builder
 
@@ -76,10 +189,46 @@ func (m model) View() string {
 		return b.String()
 	}
 
+	// If the variable picker is active, render it instead of the path list
+	if m.varPicker != nil {
+		b.WriteString(m.varPicker.View(m.viewWidth))
+		b.WriteString(truncateLine(" Enter: edit variable | Esc: cancel", m.viewWidth))
+		return b.String()
+	}
+
+	// If the merge view is active, render it instead of the path list
+	if m.mergeView != nil {
+		b.WriteString(m.mergeView.View(m.viewWidth))
+		b.WriteString(truncateLine(" Space: toggle | a/o: all/none | Tab: switch view | Enter: merge | Esc: cancel", m.viewWidth))
+		return b.String()
+	}
+
+	// If the diff view is active, render it instead of the path list
+	if m.diffView != nil {
+		b.WriteString(m.diffView.View(m.viewWidth))
+		helpBar := " Arrows/PgUp/PgDn: scroll | Esc/D: close"
+		if m.diffView.returnToQuit {
+			helpBar = " Arrows/PgUp/PgDn: scroll | Esc/D: back to quit prompt"
+		}
+		b.WriteString(truncateLine(helpBar, m.viewWidth))
+		return b.String()
+	}
+
 	// If browser is active, render it instead of the path list
 	if m.browser != nil {
 		b.WriteString(m.browser.View(m.viewWidth))
-		helpBar := " Enter: open | a-z/A-Z: jump fwd/back | Tab: select | Esc: cancel"
+		helpBar := " Enter: open | a-z/A-Z: jump fwd/back | Tab: select | Ctrl+E: type path | Esc: cancel"
+		if len(helpBar) > m.viewWidth {
+			helpBar = helpBar[:m.viewWidth-3] + "..."
+		}
+		b.WriteString(helpBar)
+		return b.String()
+	}
+
+	// If line editor is active, render it instead of the path list
+	if m.editor != nil {
+		b.WriteString(m.editor.View(m.viewWidth))
+		helpBar := " Enter: accept | Tab: complete | Ctrl+W: delete component | Esc: cancel"
 		if len(helpBar) > m.viewWidth {
 			helpBar = helpBar[:m.viewWidth-3] + "..."
 		}
@@ -87,16 +236,23 @@ func (m model) View() string {
 		return b.String()
 	}
 
-	// Calculate visible range and scrollbar
-	start, end := m.list.VisibleRange(len(m.paths))
-	scrollbar := m.list.RenderScrollbar(len(m.paths))
+	// Calculate visible range and scrollbar (over the filtered rows when filtering)
+	rows := m.rows()
+	start, end := m.list.VisibleRange(len(rows))
+	scrollbar := m.list.RenderScrollbar(len(rows))
+	severities := entrySeverities(m.auditPaths(), len(m.paths))
+	selectedRows := m.selectedRows()
+	selected := make([]bool, len(rows))
+	for _, row := range selectedRows {
+		selected[row] = true
+	}
 
 	// Render visible paths with scrollbar
 	for i := start; i < end; i++ {
-		entry := m.paths[i]
-		prefix := renderEntryPrefix(entry, i == m.list.cursor)
-		path := entry.path
-		pathRunes := []rune(path)
+		entry := m.paths[rows[i]]
+		prefix := renderEntryPrefix(entry, i == m.list.cursor, severities[rows[i]])
+		rawLen := len([]rune(entry.path))
+		pathRunes := []rune(displayPath(entry, m.showExpanded, m.backend.Var()))
 		pathLen := len(pathRunes)
 		// Available width for path content: total - cursor(2) - scrollbar(2) - possible markers(2)
 		contentWidth := m.viewWidth - 4 // cursor + scrollbar + space
@@ -130,7 +286,6 @@ func (m model) View() string {
 		if len(visibleRunes) > displayWidth {
 			visibleRunes = visibleRunes[:displayWidth]
 		}
-		visiblePath := string(visibleRunes)
 
 		// Build line with markers (green colored)
 		var line strings.Builder
@@ -138,12 +293,32 @@ func (m model) View() string {
 		if hasLeft {
 			line.WriteString(ansiGreen + "<" + ansiReset)
 		}
+		// Split the visible runes into the raw path and the expanded suffix
+		hStart := min(m.list.hOffset, pathLen)
+		rawVisible := min(max(rawLen-hStart, 0), len(visibleRunes))
 		style, needsReset := renderEntryStyle(entry)
+		if selected[i] {
+			// Selected entries are shown in reverse video across the whole line
+			style += ansiReverse
+			needsReset = true
+		}
 		line.WriteString(style)
-		line.WriteString(visiblePath)
+		if marks := searchMatches(entry.path, m.searchQuery); marks != nil && rawVisible > 0 {
+			// Highlight search matches within the visible part of the path
+			line.WriteString(renderHighlighted(visibleRunes[:rawVisible], marks[hStart:hStart+rawVisible], selected[i]))
+		} else {
+			line.WriteString(string(visibleRunes[:rawVisible]))
+		}
 		if needsReset {
 			line.WriteString(ansiReset)
 		}
+		if rawVisible < len(visibleRunes) {
+			suffixStyle := ansiGrey
+			if selected[i] {
+				suffixStyle += ansiReverse
+			}
+			line.WriteString(suffixStyle + string(visibleRunes[rawVisible:]) + ansiReset)
+		}
 
 		// Pad to align right marker and scrollbar
 		currentLen := 2 + len(visibleRunes) // cursor + content (rune count)
@@ -154,7 +329,9 @@ func (m model) View() string {
 		if hasRight {
 			targetLen-- // leave room for > marker
 		}
-		if padding := targetLen - currentLen; padding > 0 {
+		if padding := targetLen - currentLen; padding > 0 && selected[i] {
+			line.WriteString(ansiReverse + strings.Repeat(" ", padding) + ansiReset)
+		} else if padding > 0 {
 			line.WriteString(strings.Repeat(" ", padding))
 		}
 		if hasRight {
@@ -180,17 +357,37 @@ func (m model) View() string {
 		b.WriteString(strings.Repeat(" ", m.viewWidth-1) + scrollChar + "\n")
 	}
 
-	// Warning if not elevated in registry mode
-	if m.registryMode && !m.elevated {
-		warning := ansiYellow + " Warning: Not running as Administrator - system PATH changes will fail" + ansiReset
-		b.WriteString(warning + "\n")
+	if m.pane != paneNone {
+		b.WriteString(m.renderPane(m.paneHeight()))
+	}
+
+	// Warning if not elevated in registry mode, or about inherited entries in file-based stores
+	if warning := m.modeWarning(); warning != "" {
+		if len(warning) > m.viewWidth {
+			warning = warning[:m.viewWidth-3] + "..."
+		}
+		b.WriteString(ansiYellow + warning + ansiReset + "\n")
 	}
 
 	// Help bar or prompt
 	if m.prompt != nil {
 		b.WriteString(m.prompt.View())
+	} else if m.pick != nil {
+		b.WriteString(truncateLine(" Move to "+m.pick.target+": pick an entry | Enter/P: above it | p: below it | Esc: cancel", m.viewWidth))
+	} else if m.typingWhich {
+		b.WriteString(truncateLine(" which: "+m.whichInput+"_", m.viewWidth))
+	} else if m.searching || m.searchQuery != "" {
+		matches := 0
+		for _, p := range m.paths {
+			if matchesSearch(p.path, m.searchQuery) {
+				matches++
+			}
+		}
+		b.WriteString(renderSearchBar(m.searchQuery, m.searching, m.filtered, matches, m.viewWidth))
+	} else if len(selectedRows) > 0 {
+		b.WriteString(renderSelectionBar(len(selectedRows), m.visual, hasSection(m.backend, "system"), m.viewWidth))
 	} else {
-		b.WriteString(renderHelpBar(m.registryMode, m.viewWidth))
+		b.WriteString(renderHelpBar(hasSection(m.backend, "system"), m.backend.Var(), m.viewWidth))
 	}
 
 	return b.String()
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// updateWhichInput handles keys while a command name is typed for the which pane
+func (m model) updateWhichInput(key string, runes []rune) model {
+	switch key {
+	case keyEnter:
+		m.typingWhich = false
+		m.whichCommand = strings.TrimSpace(m.whichInput)
+		if m.whichCommand == "" {
+			m.pane = paneNone
+		} else {
+			m.pane = paneWhich
+		}
+		m.resizeList()
+	case keyEsc:
+		m.typingWhich = false
+	case "backspace":
+		if m.whichInput != "" {
+			_, size := utf8.DecodeLastRuneInString(m.whichInput)
+			m.whichInput = m.whichInput[:len(m.whichInput)-size]
+		}
+	case keySelect:
+		m.whichInput = completeCommand(m.analyzeShadows(), m.whichInput)
+	default:
+		m.whichInput += string(runes)
+	}
+	return m
+}
+
+// completeCommand extends prefix to the longest prefix shared by all commands on PATH
+// that start with it
+func completeCommand(a *shadowAnalysis, prefix string) string {
+	var names []string
+	for name := range a.providers {
+		if strings.HasPrefix(name, commandKey(prefix)) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return prefix
+	}
+	sort.Strings(names)
+	first, last := names[0], names[len(names)-1]
+	i := 0
+	for i < len(first) && i < len(last) && first[i] == last[i] {
+		i++
+	}
+	return prefix + first[len(commandKey(prefix)):i]
+}
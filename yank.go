@@ -0,0 +1,190 @@
+package main
+
+import (
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Yank and paste: yy copies the selected entries (or the cursor entry) to the register
+// and, with OSC 52, to the system clipboard; dd does the same and removes them. p and P
+// paste the register below or above the cursor entry, and text pasted from the
+// terminal (bracketed paste) is added the same way. Pasted entries are new entries.
+
+// terminal is the device the TUI is drawn on. Bubble Tea writes each frame in a
+// single write and commands run concurrently with it, so writes are serialized: a
+// clipboard sequence can't land in the middle of a frame. It is still an *os.File
+// underneath, so Bubble Tea detects the terminal and its size as before.
+type terminal struct {
+	*os.File
+	mu sync.Mutex
+}
+
+func (t *terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.File.Write(p)
+}
+
+// copyToClipboard returns a command that sets the system clipboard to text with an
+// OSC 52 sequence written to the terminal in one write, between frames (see terminal).
+// Terminal multiplexers need it wrapped.
+func copyToClipboard(w io.Writer, text string) tea.Cmd {
+	if w == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		seq := osc52.New(text)
+		if os.Getenv("TMUX") != "" {
+			seq = seq.Tmux()
+		} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+			seq = seq.Screen()
+		}
+		seq.WriteTo(w)
+		return nil
+	}
+}
+
+// yankSelection copies the selected entries (or the cursor entry) to the register and
+// the system clipboard, and clears the selection
+func (m *model) yankSelection() tea.Cmd {
+	cmd := m.yank(m.selection())
+	m.clearSelection()
+	return cmd
+}
+
+// yank copies the paths of the selected entries to the register and, as a value of
+// the variable, to the system clipboard
+func (m *model) yank(selected []bool) tea.Cmd {
+	var yanked []string
+	for i, sel := range selected {
+		if sel {
+			yanked = append(yanked, m.paths[i].path)
+		}
+	}
+	if len(yanked) == 0 {
+		return nil
+	}
+	m.register = yanked
+	return copyToClipboard(m.clipboard, m.backend.Var().join(yanked))
+}
+
+// cutSelection yanks the selected entries (or the cursor entry) and removes them:
+// entries added in this session are dropped, the others are marked for deletion
+func (m *model) cutSelection() tea.Cmd {
+	single := !m.hasSelection()
+	selected := m.editableSelection()
+	if !slices.Contains(selected, true) {
+		return nil
+	}
+	cmd := m.yank(selected)
+	m.checkpoint()
+	var kept []pathEntry
+	for i, p := range m.paths {
+		if selected[i] && p.added {
+			continue
+		}
+		if selected[i] {
+			p.deleted = true
+			p.selected = false
+		}
+		kept = append(kept, p)
+	}
+	if single && len(kept) == len(m.paths) {
+		// Like dd in vim, a repeated dd cuts the next entry
+		m.list.MoveDown(len(m.rows()))
+	}
+	m.paths = kept
+	m.clampCursor()
+	return cmd
+}
+
+// pasteEntries inserts new entries with the given paths below (or above) the cursor
+// entry, in its section. On an inherited entry they go to the end of the user section.
+func (m *model) pasteEntries(paths []string, below bool) {
+	if len(paths) == 0 {
+		return
+	}
+	m.materializeSelection()
+	m.checkpoint()
+	v := m.backend.Var()
+	source, at := defaultSource(m.backend), -1
+	if idx := m.cursorIndex(); idx >= 0 && !inherited(m.backend, m.paths[idx]) {
+		source, at = m.paths[idx].source, idx
+		if below {
+			at++
+		}
+	} else {
+		_, at = sectionBounds(m.paths, source)
+	}
+	for n, p := range paths {
+		m.paths = insertPathEntryAt(m.paths, at+n, pathEntry{
+			path:     p,
+			source:   source,
+			modified: true,
+			added:    true,
+			exists:   v.exists(p),
+		})
+	}
+	if row := m.rowOf(at); row >= 0 {
+		m.list.cursor = row
+	}
+	m.clampCursor()
+	m.list.EnsureVisible()
+}
+
+// pastedPaths splits text pasted into the list into entries: one per line, and lines
+// holding a value of v are split at its separator
+func pastedPaths(text string, v pathVar) []string {
+	var paths []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		for _, p := range v.split(strings.TrimSpace(line)) {
+			if p = strings.TrimSpace(p); p != "" {
+				paths = append(paths, p)
+			}
+		}
+	}
+	return paths
+}
+
+// duplicateSelection inserts a copy of each selected entry (or the cursor entry) right
+// after it, as a new entry, moves the cursor to the copy of the cursor entry and
+// clears the selection
+func (m *model) duplicateSelection() {
+	selected := m.editableSelection()
+	if !slices.Contains(selected, true) {
+		return
+	}
+	m.checkpoint()
+	v := m.backend.Var()
+	cur, newCur := m.cursorIndex(), -1
+	var paths []pathEntry
+	for i, p := range m.paths {
+		p.selected = false
+		paths = append(paths, p)
+		if !selected[i] {
+			continue
+		}
+		if i == cur {
+			newCur = len(paths)
+		}
+		paths = append(paths, pathEntry{
+			path:     p.path,
+			source:   p.source,
+			modified: true,
+			added:    true,
+			exists:   v.exists(p.path),
+		})
+	}
+	m.paths = paths
+	if row := m.rowOf(newCur); row >= 0 {
+		m.list.cursor = row
+	}
+	m.clampCursor()
+	m.list.EnsureVisible()
+}